@@ -0,0 +1,71 @@
+package bhttp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaseURLOption configures [BaseURL].
+type BaseURLOption func(*baseURLOptions)
+
+type baseURLOptions struct {
+	trustForwarded bool
+}
+
+// TrustForwardedHeaders makes [BaseURL] take the scheme and host from the X-Forwarded-Proto and
+// X-Forwarded-Host headers. Only use it when a trusted proxy, such as API Gateway or a load balancer,
+// sets or strips these headers, since clients could otherwise inject their own host into generated links.
+func TrustForwardedHeaders() BaseURLOption {
+	return func(o *baseURLOptions) { o.trustForwarded = true }
+}
+
+// BaseURL returns the absolute url under which the request's route is served, to be used as the base for
+// [ServeMux.ReverseURL]. The scheme and host are those of the request itself, or of the forwarded headers
+// with [TrustForwardedHeaders]. Forwarded schemes other than "http" and "https", and malformed forwarded
+// hosts, are ignored. The path consists of 'basePath', for example an API Gateway stage that was removed
+// before the request reached the app, followed by the prefix of any mount the request was routed through
+// (see [MountPrefix]).
+func BaseURL(r *http.Request, basePath string, opts ...BaseURLOption) *url.URL {
+	var o baseURLOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	host := r.Host
+
+	if o.trustForwarded {
+		if proto := strings.ToLower(firstHeaderValue(r.Header, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+
+		if fwd := firstHeaderValue(r.Header, "X-Forwarded-Host"); validForwardedHost(fwd) {
+			host = fwd
+		}
+	}
+
+	path := strings.TrimSuffix(basePath, "/") + MountPrefix(r.Context())
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return &url.URL{Scheme: scheme, Host: host, Path: path}
+}
+
+// validForwardedHost reports whether a forwarded host is a plain host with an optional port, that cannot
+// change the meaning of the url it is put in.
+func validForwardedHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, "/\\@?# \t")
+}
+
+// firstHeaderValue returns the first element of a possibly comma-separated header value, as added by
+// every proxy in a chain.
+func firstHeaderValue(h http.Header, key string) string {
+	first, _, _ := strings.Cut(h.Get(key), ",")
+	return strings.TrimSpace(first)
+}
@@ -0,0 +1,77 @@
+package bhttp_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaseURL(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		header   http.Header
+		tls      bool
+		basePath string
+		opts     []bhttp.BaseURLOption
+		out      string
+	}{
+		{"plain", nil, false, "", nil, "http://example.com"},
+		{"tls", nil, true, "", nil, "https://example.com"},
+		{"base path", nil, false, "/prod", nil, "http://example.com/prod"},
+		{"forwarded", http.Header{
+			"X-Forwarded-Proto": {"HTTPS, http"},
+			"X-Forwarded-Host":  {"api.example.com, proxy.internal"},
+		}, false, "prod/", []bhttp.BaseURLOption{bhttp.TrustForwardedHeaders()}, "https://api.example.com/prod"},
+		{"forwarded untrusted", http.Header{
+			"X-Forwarded-Proto": {"https"},
+			"X-Forwarded-Host":  {"evil.example.com"},
+		}, false, "", nil, "http://example.com"},
+		{"forwarded invalid", http.Header{
+			"X-Forwarded-Proto": {"javascript"},
+			"X-Forwarded-Host":  {"evil.example.com/x?"},
+		}, true, "", []bhttp.BaseURLOption{bhttp.TrustForwardedHeaders()}, "https://example.com"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+
+			if test.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			require.Equal(t, test.out, bhttp.BaseURL(req, test.basePath, test.opts...).String())
+		})
+	}
+}
+
+func TestReverseURLUnderMount(t *testing.T) {
+	inner := bhttp.NewServeMux()
+	inner.HandleFunc("GET /users/{id}", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		loc, err := inner.ReverseURL(bhttp.BaseURL(r, "/prod", bhttp.TrustForwardedHeaders()), "get-user", "2")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(w, loc)
+
+		return nil
+	}, bhttp.Name("get-user"))
+
+	mux := bhttp.NewServeMux()
+	mux.MountStd("/api", inner)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "https://example.com/prod/api/users/2", rec.Body.String())
+}
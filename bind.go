@@ -0,0 +1,208 @@
+package bhttp
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// bindSources are the struct tags understood by [Bind], with how their values are named in errors.
+var bindSources = []struct { //nolint:gochecknoglobals
+	tag  string
+	kind string
+}{
+	{"path", "path parameter"},
+	{"query", "query parameter"},
+	{"header", "header"},
+	{"cookie", "cookie"},
+}
+
+// bindFields caches the fields of struct types that have been bound before.
+var bindFields sync.Map //nolint:gochecknoglobals
+
+// bindField is a struct field that is filled by [Bind].
+type bindField struct {
+	index []int
+	tag   string
+	kind  string
+	name  string
+	def   *string
+}
+
+// FieldError describes a single request value that could not be bound.
+type FieldError struct {
+	Field string // name of the struct field.
+	Kind  string // "path parameter", "query parameter", "header" or "cookie".
+	Name  string // name of the request value, e.g. the query parameter name.
+	Err   error  // why the value could not be parsed.
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.Kind, e.Name, e.Err)
+}
+
+// BindError is returned by [Bind], wrapped in a [CodeBadRequest] error, and names every value that could
+// not be bound. It can be retrieved with [errors.As] to render the fields individually.
+type BindError struct {
+	Fields []FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		msgs = append(msgs, field.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Bind fills the struct pointed to by 'dst' from the request. Exported fields are tagged with the source
+// of their value: `path:"id"`, `query:"page"`, `header:"X-Tenant"` or `cookie:"sid"`. A `default:"..."`
+// tag provides the value when the request does not.
+//
+// Fields can be of any type supported by typed routes: strings, booleans, numbers and types that
+// implement [encoding.TextUnmarshaler]. Pointer fields stay nil when the value is absent, and slice fields
+// receive every value of a repeated query parameter or header. When values cannot be parsed, Bind returns
+// a [CodeBadRequest] error wrapping a [*BindError] that names all of them. Other errors indicate a mistake
+// in the definition of 'dst'.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.Newf("bind destination must be a non-nil pointer to a struct, got: %T", dst)
+	}
+
+	fields, err := bindFieldsOf(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	var berr BindError
+
+	for _, field := range fields {
+		vals := field.values(r)
+		if len(vals) < 1 {
+			if field.def == nil {
+				continue
+			}
+
+			vals = []string{*field.def}
+		}
+
+		if err := bindValue(v.Elem().FieldByIndex(field.index), vals); err != nil {
+			berr.Fields = append(berr.Fields, FieldError{
+				Field: v.Elem().Type().FieldByIndex(field.index).Name,
+				Kind:  field.kind,
+				Name:  field.name,
+				Err:   err,
+			})
+		}
+	}
+
+	if len(berr.Fields) > 0 {
+		return NewError(CodeBadRequest, &berr)
+	}
+
+	return nil
+}
+
+// values returns the request values for the field, or nothing when the value is absent.
+func (f bindField) values(r *http.Request) []string {
+	switch f.tag {
+	case "path":
+		if v := r.PathValue(f.name); v != "" {
+			return []string{v}
+		}
+	case "query":
+		if vals := r.URL.Query()[f.name]; len(vals) > 0 && (len(vals) > 1 || vals[0] != "") {
+			return vals
+		}
+	case "header":
+		return r.Header.Values(f.name)
+	case "cookie":
+		if c, err := r.Cookie(f.name); err == nil {
+			return []string{c.Value}
+		}
+	}
+
+	return nil
+}
+
+// bindValue sets 'v' from one or more textual values.
+func bindValue(v reflect.Value, vals []string) error {
+	switch {
+	case canParseValue(v.Type()):
+		return parseValue(v, vals[0])
+	case v.Kind() == reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := parseValue(elem.Elem(), vals[0]); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+	default:
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := parseValue(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+
+		v.Set(slice)
+	}
+
+	return nil
+}
+
+// bindFieldsOf returns the bound fields of struct type 'typ'.
+func bindFieldsOf(typ reflect.Type) ([]bindField, error) {
+	if cached, ok := bindFields.Load(typ); ok {
+		return cached.([]bindField), nil //nolint:forcetypeassert
+	}
+
+	var fields []bindField
+
+	for _, field := range reflect.VisibleFields(typ) {
+		for _, src := range bindSources {
+			name, ok := field.Tag.Lookup(src.tag)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case !field.IsExported():
+				return nil, errors.Newf("field %s with %s tag must be exported", field.Name, src.tag)
+			case name == "":
+				return nil, errors.Newf("field %s has an empty %s tag", field.Name, src.tag)
+			case !canBindValue(field.Type):
+				return nil, errors.Newf("field %s has unsupported type: %s", field.Name, field.Type)
+			}
+
+			bf := bindField{index: field.Index, tag: src.tag, kind: src.kind, name: name}
+			if def, ok := field.Tag.Lookup("default"); ok {
+				bf.def = &def
+			}
+
+			fields = append(fields, bf)
+		}
+	}
+
+	bindFields.Store(typ, fields)
+
+	return fields, nil
+}
+
+// canBindValue reports whether [bindValue] supports values of type 't'.
+func canBindValue(t reflect.Type) bool {
+	switch {
+	case canParseValue(t):
+		return true
+	case t.Kind() == reflect.Pointer, t.Kind() == reflect.Slice:
+		return canParseValue(t.Elem())
+	default:
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package bhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type listInput struct {
+	Org     string     `path:"org"`
+	Page    int        `query:"page" default:"1"`
+	Tags    []string   `query:"tag"`
+	Limit   *uint      `query:"limit"`
+	Tenant  string     `header:"X-Tenant"`
+	Client  netip.Addr `header:"X-Client-Ip"`
+	Session string     `cookie:"sid"`
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?tag=a&tag=b&limit=5", nil)
+	req.SetPathValue("org", "acme")
+	req.Header.Set("X-Tenant", "t1")
+	req.Header.Set("X-Client-Ip", "10.0.0.1")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+
+	var in listInput
+	require.NoError(t, bhttp.Bind(req, &in))
+
+	limit := uint(5)
+	require.Equal(t, listInput{
+		Org:     "acme",
+		Page:    1,
+		Tags:    []string{"a", "b"},
+		Limit:   &limit,
+		Tenant:  "t1",
+		Client:  netip.MustParseAddr("10.0.0.1"),
+		Session: "s1",
+	}, in)
+
+	t.Run("absent optional values", func(t *testing.T) {
+		var in listInput
+		require.NoError(t, bhttp.Bind(httptest.NewRequest(http.MethodGet, "/", nil), &in))
+		require.Nil(t, in.Limit)
+		require.Nil(t, in.Tags)
+		require.Equal(t, 1, in.Page)
+	})
+}
+
+func TestBindErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?page=x&limit=-1", nil)
+	req.Header.Set("X-Client-Ip", "nope")
+
+	var in listInput
+	err := bhttp.Bind(req, &in)
+	require.Equal(t, bhttp.CodeBadRequest, bhttp.CodeOf(err))
+
+	var berr *bhttp.BindError
+	require.True(t, errors.As(err, &berr))
+	require.Len(t, berr.Fields, 3)
+	require.Equal(t, "Page", berr.Fields[0].Field)
+	require.Equal(t, "limit", berr.Fields[1].Name)
+	require.Equal(t, "header", berr.Fields[2].Kind)
+	require.Contains(t, err.Error(), `invalid query parameter "page": must be an integer; `+
+		`invalid query parameter "limit": must be a non-negative integer; invalid header "X-Client-Ip": `)
+
+	t.Run("invalid destination", func(t *testing.T) {
+		require.ErrorContains(t, bhttp.Bind(req, in), "must be a non-nil pointer to a struct")
+
+		var bad struct {
+			M map[string]string `query:"m"`
+		}
+		require.ErrorContains(t, bhttp.Bind(req, &bad), "field M has unsupported type")
+	})
+}
@@ -73,6 +73,22 @@ func WithHealthHandler(h func(http.ResponseWriter, *http.Request)) Option {
 	}
 }
 
+// WithoutRequestID leaves out the [bhttp.RequestID] middleware that every app gets by default. Responses
+// then no longer echo the X-Request-Id header or include the id in error responses.
+func WithoutRequestID() Option {
+	return func(c *AppConfig) {
+		c.DisableRequestID = true
+	}
+}
+
+// WithoutRouteSpans keeps the span names of the HTTP instrumentation, instead of naming server spans
+// after the matched route pattern, as apps do by default.
+func WithoutRouteSpans() Option {
+	return func(c *AppConfig) {
+		c.DisableRouteSpans = true
+	}
+}
+
 // FxOptions builds the []fx.Option used by both NewApp and blwatest.New.
 func FxOptions[E Environment](routing any, opts ...Option) []fx.Option {
 	var cfg AppConfig
@@ -119,7 +135,7 @@ func FxOptions[E Environment](routing any, opts ...Option) []fx.Option {
 // Example:
 //
 //	blwa.NewApp[Env](func(m *blwa.Mux, h *Handlers) {
-//	    m.HandleFunc("GET /items", h.ListItems, "list-items")
+//	    m.HandleFunc("GET /items", h.ListItems, bhttp.Name("list-items"))
 //	},
 //	    blwa.WithAWSClient(func(cfg aws.Config) *dynamodb.Client {
 //	        return dynamodb.NewFromConfig(cfg)
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/advdv/bhttp"
 	"github.com/advdv/bhttp/blwa"
 	"github.com/advdv/bhttp/blwa/blwatest"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -27,7 +28,7 @@ func TestApp_ContextFeatures(t *testing.T) {
 			m.HandleFunc("GET /context", h.TestContext)
 			m.HandleFunc("GET /aws", h.TestAWS)
 			m.HandleFunc("POST /items", h.CreateItem)
-			m.HandleFunc("GET /items/{id}", h.GetItem, "get-item")
+			m.HandleFunc("GET /items/{id}", h.GetItem, bhttp.Name("get-item"))
 		},
 		blwa.WithAWSClient(func(cfg aws.Config) *dynamodb.Client { return dynamodb.NewFromConfig(cfg) }),
 		blwa.WithAWSClient(func(cfg aws.Config) *s3.Client { return s3.NewFromConfig(cfg) }),
@@ -147,4 +148,46 @@ func TestApp_ContextFeatures(t *testing.T) {
 			t.Errorf("expected 200, got %d", resp.StatusCode)
 		}
 	})
+	t.Run("Request_ID", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/not-found", nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		req.Header.Set("x-amzn-lambda-context", `{"request_id":"lambda-req-1"}`)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET /not-found failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if got := resp.Header.Get(bhttp.DefaultRequestIDHeader); got != "lambda-req-1" {
+			t.Errorf("expected the Lambda request id, got %q", got)
+		}
+		if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "(request id: lambda-req-1)") {
+			t.Errorf("expected the request id in the error body, got %q", body)
+		}
+	})
+}
+
+func TestApp_WithoutRequestID(t *testing.T) {
+	setTestEnvForTestEnv(t, 18088)
+
+	app := blwatest.New[TestEnv](t, func(*blwa.Mux) {}, blwa.WithoutRequestID())
+	app.RequireStart()
+	t.Cleanup(app.RequireStop)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := doGet(context.Background(), client, "http://localhost:18088/not-found")
+	if err != nil {
+		t.Fatalf("GET /not-found failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get(bhttp.DefaultRequestIDHeader); got != "" {
+		t.Errorf("expected no request id header, got %q", got)
+	}
+	if body, _ := io.ReadAll(resp.Body); strings.Contains(string(body), "request id") {
+		t.Errorf("expected no request id in the error body, got %q", body)
+	}
 }
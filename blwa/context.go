@@ -89,6 +89,14 @@ func withLWAContext() bhttp.Middleware {
 	}
 }
 
+// lwaRequestID returns the id of the Lambda invocation, or the empty string outside of Lambda.
+func lwaRequestID(r *http.Request) string {
+	if lc := LWA(r.Context()); lc != nil {
+		return lc.RequestID
+	}
+	return ""
+}
+
 func requestDepFromContext(ctx context.Context) *requestDep {
 	d, ok := ctx.Value(ctxKeyRequestDep).(*requestDep)
 	if !ok {
@@ -104,10 +112,15 @@ func LWA(ctx context.Context) *LWAContext {
 	return lc
 }
 
-// Log returns a trace-correlated zap logger from the context.
+// Log returns a trace-correlated zap logger from the context. Its entries include the
+// request id of [bhttp.RequestID] as the "request_id" field.
 func Log(ctx context.Context) *zap.Logger {
 	d := requestDepFromContext(ctx)
-	return d.logger.With(traceFields(ctx)...)
+	fields := traceFields(ctx)
+	if id := bhttp.RequestIDFrom(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	return d.logger.With(fields...)
 }
 
 // Span returns the current trace span from the context.
@@ -8,7 +8,7 @@
 //
 //	blwa.NewApp[Env](func(m *blwa.Mux, h *Handlers) {
 //	    m.HandleFunc("GET /items", h.ListItems)
-//	    m.HandleFunc("GET /items/{id}", h.GetItem, "get-item")
+//	    m.HandleFunc("GET /items/{id}", h.GetItem, bhttp.Name("get-item"))
 //	},
 //	    blwa.WithAWSClient(dynamodb.NewFromConfig),
 //	    blwa.WithFx(fx.Provide(NewHandlers)),
@@ -105,6 +105,13 @@
 //   - [Log] - trace-correlated zap logger
 //   - [Span] - current OpenTelemetry span for custom instrumentation
 //   - [LWA] - Lambda execution context (request ID, deadline, etc.)
+//   - [bhttp.RequestIDFrom] - the request id, also logged by [Log] as "request_id"
+//
+// Every request is identified by the X-Request-Id header of the client, or else
+// by the request id of the Lambda invocation. The id is echoed on responses,
+// included in error responses, and sent along with outbound requests of the
+// HTTP client. Apps that render their own error bodies, or rely on the earlier
+// responses, can leave the middleware out with [WithoutRequestID].
 //
 // # Tracing
 //
@@ -114,7 +121,11 @@
 //   - "xrayudp": X-Ray UDP exporter for Lambda with proper trace ID format
 //
 // The tracer provider and propagator are injected explicitly (no globals),
-// allowing for proper testing and isolation.
+// allowing for proper testing and isolation. Server spans are named after the
+// matched route pattern (e.g. "GET /items/{id}") and carry the http.route
+// attribute, so traces group by route rather than by concrete path. Use
+// [WithoutRouteSpans] to keep the span names of earlier versions, for example
+// when dashboards or alerts select spans by name.
 //
 // When BW_GATEWAY_ACCESS_LOG_GROUP is set (injected automatically by
 // bwcdkrestgateway), the log group is added to trace segments via the
@@ -259,6 +270,47 @@
 // tracing, so outbound requests automatically become child spans of the active
 // trace with propagated context headers.
 //
+// # Authentication
+//
+// [WithJWKS] provides the key set of [bhttp.JWTAuth], fetched over the injected
+// *http.Client so that key set fetches are traced like any other outbound
+// request:
+//
+//	blwa.NewApp[Env](func(m *blwa.Mux, jwks *bhttp.JWKS, h *Handlers) {
+//	    m.Use(bhttp.JWTAuth(jwks, bhttp.WithAudience("orders-api")))
+//	    m.HandleFunc("GET /orders", h.ListOrders, bhttp.Name("list-orders"))
+//	},
+//	    blwa.WithJWKS(jwksURL),
+//	    blwa.WithFx(fx.Provide(NewHandlers)),
+//	).Run()
+//
+// # Idempotency
+//
+// [DynamoIdempotencyStore] keeps the keys of the [bhttp.Idempotency]
+// middleware in a DynamoDB table, so retries are detected across all Lambda
+// instances. Responses over 350KB do not fit in a single item and are not
+// recorded:
+//
+//	mux.Use(bhttp.Idempotency(blwa.NewDynamoIdempotencyStore(dynamoClient, "idempotency-keys")))
+//
+// # Sessions
+//
+// [DynamoSessionStore] keeps the sessions of the [bhttp.Sessions] middleware in
+// a DynamoDB table, so that they are shared by all Lambda instances:
+//
+//	mux.Use(bhttp.Sessions(blwa.NewDynamoSessionStore(dynamoClient, "sessions"), sessionKeys))
+//
+// # Uploads
+//
+// [S3UploadSink] stores the files of a [bhttp.ParseUpload] call in an S3
+// bucket instead of the (small) Lambda ephemeral storage:
+//
+//	sink := blwa.NewS3UploadSink(s3Client, "my-uploads", "incoming/")
+//	upload, err := bhttp.ParseUpload(r, sink, bhttp.UploadLimits{
+//	    FileLimit:    10 << 20,
+//	    ContentTypes: []string{"image/*"},
+//	})
+//
 // # Timeouts
 //
 // HTTP server timeouts are configured based on BW_LAMBDA_TIMEOUT to match the
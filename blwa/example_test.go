@@ -94,7 +94,7 @@ func Example() {
 	blwa.NewApp[Env](
 		func(m *blwa.Mux, h *ItemHandlers) {
 			m.HandleFunc("GET /items", h.ListItems)
-			m.HandleFunc("GET /items/{id}", h.GetItem, "get-item")
+			m.HandleFunc("GET /items/{id}", h.GetItem, bhttp.Name("get-item"))
 			m.HandleFunc("POST /items", h.CreateItem)
 		},
 		// Local region DynamoDB client - injected directly as *dynamodb.Client
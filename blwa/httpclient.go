@@ -3,20 +3,23 @@ package blwa
 import (
 	"net/http"
 
+	"github.com/advdv/bhttp"
 	"github.com/carlmjohnson/requests"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
 )
 
 // NewHTTPTransport creates an HTTP RoundTripper instrumented with OpenTelemetry tracing.
 // The TracerProvider and Propagator are explicitly injected to avoid global state.
 // Use this when you need a custom *http.Client but still want outbound request tracing.
+// Outbound requests also carry the request id of the inbound request, see [bhttp.PropagateRequestID].
 func NewHTTPTransport(tp trace.TracerProvider, prop propagation.TextMapPropagator) http.RoundTripper {
-	return otelhttp.NewTransport(http.DefaultTransport,
+	return bhttp.PropagateRequestID(otelhttp.NewTransport(http.DefaultTransport,
 		otelhttp.WithTracerProvider(tp),
 		otelhttp.WithPropagators(prop),
-	)
+	))
 }
 
 // NewHTTPClient creates an *http.Client that uses the instrumented transport.
@@ -25,6 +28,19 @@ func NewHTTPClient(t http.RoundTripper) *http.Client {
 	return &http.Client{Transport: t}
 }
 
+// WithJWKS provides a *[bhttp.JWKS] for the key set at 'url', fetched over the injected *http.Client so
+// that key set fetches are traced like any other outbound request. The routing function, or any other
+// constructor, can then request it:
+//
+//	blwa.NewApp[Env](func(m *blwa.Mux, jwks *bhttp.JWKS) {
+//	    m.Use(bhttp.JWTAuth(jwks, bhttp.WithAudience("orders-api")))
+//	}, blwa.WithJWKS("https://login.example.com/.well-known/jwks.json"))
+func WithJWKS(url string, opts ...bhttp.JWKSOption) Option {
+	return WithFx(fx.Provide(func(client *http.Client) *bhttp.JWKS {
+		return bhttp.NewJWKS(url, append([]bhttp.JWKSOption{bhttp.WithJWKSClient(client)}, opts...)...)
+	}))
+}
+
 // newRequestBuilder creates a base [requests.Builder] with the instrumented transport.
 // This is not exported; handlers access it via [Runtime.NewRequest].
 func newRequestBuilder(t http.RoundTripper) *requests.Builder {
@@ -6,8 +6,11 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/advdv/bhttp"
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
 )
 
 func TestNewHTTPTransport(t *testing.T) {
@@ -166,3 +169,57 @@ func TestRuntimeNewRequest(t *testing.T) {
 		t.Errorf("expected 'from-runtime', got %q", s)
 	}
 }
+
+func TestRuntimeNewRequest_PropagatesRequestID(t *testing.T) {
+	runtime := &Runtime[testEnv]{
+		transport: NewHTTPTransport(sdktrace.NewTracerProvider(), propagation.TraceContext{}),
+	}
+
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(bhttp.DefaultRequestIDHeader)
+	}))
+	defer ts.Close()
+
+	ctx := bhttp.WithRequestID(context.Background(), "req-1")
+	if err := runtime.NewRequest().BaseURL(ts.URL).Fetch(ctx); err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	if received != "req-1" {
+		t.Errorf("expected request id 'req-1', got %q", received)
+	}
+}
+
+func TestWithJWKS(t *testing.T) {
+	var traceparent string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"oct","kid":"k1","k":"c2VjcmV0"}]}`))
+	}))
+	defer ts.Close()
+
+	var cfg AppConfig
+	WithJWKS(ts.URL)(&cfg)
+
+	var jwks *bhttp.JWKS
+
+	app := fx.New(append(cfg.FxOptions,
+		fx.NopLogger,
+		fx.Supply(fx.Annotate(sdktrace.NewTracerProvider(), fx.As(new(trace.TracerProvider)))),
+		fx.Supply(fx.Annotate(propagation.TraceContext{}, fx.As(new(propagation.TextMapPropagator)))),
+		fx.Provide(NewHTTPTransport, NewHTTPClient),
+		fx.Populate(&jwks),
+	)...)
+	if err := app.Err(); err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+
+	if _, err := jwks.Key(context.Background(), "k1", bhttp.AlgHS256); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if traceparent == "" {
+		t.Error("expected the key set to be fetched over the traced transport")
+	}
+}
@@ -0,0 +1,166 @@
+package blwa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cockroachdb/errors"
+)
+
+// DynamoDBItemAPI is the part of the DynamoDB client that is used by the stores in this package.
+type DynamoDBItemAPI interface {
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// maxDynamoIdempotencyRecord caps the size of the recorded header and body, leaving room in the 400KB
+// item limit of DynamoDB for the other attributes.
+const maxDynamoIdempotencyRecord = 350 * 1024
+
+// DynamoIdempotencyStore is a [bhttp.IdempotencyStore] that keeps idempotency keys in a DynamoDB table,
+// so that they are shared by all Lambda instances. The table must have a string partition key named
+// "pk". Enable time to live on the "expires_at" attribute to have DynamoDB remove expired keys; expired
+// keys that were not removed yet are ignored. Responses that do not fit in a single item are not
+// recorded, Save then returns [bhttp.ErrIdempotencyRecordTooLarge] so the key is released.
+//
+// The client is typically registered with [WithAWSClient]:
+//
+//	blwa.WithAWSClient(func(cfg aws.Config) *dynamodb.Client {
+//	    return dynamodb.NewFromConfig(cfg)
+//	}),
+//	blwa.WithFx(fx.Provide(func(c *dynamodb.Client, env Env) bhttp.IdempotencyStore {
+//	    return blwa.NewDynamoIdempotencyStore(c, env.IdempotencyTable)
+//	})),
+type DynamoIdempotencyStore struct {
+	client DynamoDBItemAPI
+	table  string
+}
+
+// NewDynamoIdempotencyStore inits a store that keeps keys in 'table'.
+func NewDynamoIdempotencyStore(client DynamoDBItemAPI, table string) *DynamoIdempotencyStore {
+	return &DynamoIdempotencyStore{client: client, table: table}
+}
+
+// Lock implements [bhttp.IdempotencyStore].
+func (s *DynamoIdempotencyStore) Lock(
+	ctx context.Context, key, fingerprint string, ttl time.Duration,
+) (*bhttp.IdempotencyRecord, bool, error) {
+	now := time.Now()
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"pk":          &types.AttributeValueMemberS{Value: key},
+			"fingerprint": &types.AttributeValueMemberS{Value: fingerprint},
+			"completed":   &types.AttributeValueMemberBOOL{Value: false},
+			"expires_at":  unixAttribute(now.Add(ttl)),
+		},
+		ConditionExpression:                 aws.String("attribute_not_exists(pk) OR expires_at < :now"),
+		ExpressionAttributeValues:           map[string]types.AttributeValue{":now": unixAttribute(now)},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var condErr *types.ConditionalCheckFailedException
+	switch {
+	case errors.As(err, &condErr):
+		rec, err := idempotencyRecordFromItem(condErr.Item)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return rec, false, nil
+	case err != nil:
+		return nil, false, errors.Wrapf(err, "failed to lock idempotency key %q", key)
+	}
+
+	return nil, true, nil
+}
+
+// Save implements [bhttp.IdempotencyStore].
+func (s *DynamoIdempotencyStore) Save(ctx context.Context, key string, rec *bhttp.IdempotencyRecord, ttl time.Duration) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal header")
+	}
+
+	if size := len(header) + len(rec.Body); size > maxDynamoIdempotencyRecord {
+		return errors.Wrapf(bhttp.ErrIdempotencyRecordTooLarge,
+			"response for idempotency key %q is %d bytes, exceeds %d", key, size, maxDynamoIdempotencyRecord)
+	}
+
+	if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"pk":          &types.AttributeValueMemberS{Value: key},
+			"fingerprint": &types.AttributeValueMemberS{Value: rec.Fingerprint},
+			"completed":   &types.AttributeValueMemberBOOL{Value: rec.Completed},
+			"status":      &types.AttributeValueMemberN{Value: strconv.Itoa(rec.Status)},
+			"header":      &types.AttributeValueMemberS{Value: string(header)},
+			"body":        &types.AttributeValueMemberB{Value: rec.Body},
+			"expires_at":  unixAttribute(time.Now().Add(ttl)),
+		},
+	}); err != nil {
+		return errors.Wrapf(err, "failed to save idempotency key %q", key)
+	}
+
+	return nil
+}
+
+// Release implements [bhttp.IdempotencyStore].
+func (s *DynamoIdempotencyStore) Release(ctx context.Context, key string) error {
+	if _, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: key}},
+	}); err != nil {
+		return errors.Wrapf(err, "failed to release idempotency key %q", key)
+	}
+
+	return nil
+}
+
+// idempotencyRecordFromItem decodes the record that was stored by [DynamoIdempotencyStore].
+func idempotencyRecordFromItem(item map[string]types.AttributeValue) (*bhttp.IdempotencyRecord, error) {
+	rec := &bhttp.IdempotencyRecord{}
+
+	if v, ok := item["fingerprint"].(*types.AttributeValueMemberS); ok {
+		rec.Fingerprint = v.Value
+	}
+
+	if v, ok := item["completed"].(*types.AttributeValueMemberBOOL); ok {
+		rec.Completed = v.Value
+	}
+
+	if v, ok := item["status"].(*types.AttributeValueMemberN); ok {
+		status, err := strconv.Atoi(v.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode status")
+		}
+
+		rec.Status = status
+	}
+
+	if v, ok := item["header"].(*types.AttributeValueMemberS); ok {
+		rec.Header = http.Header{}
+		if err := json.Unmarshal([]byte(v.Value), &rec.Header); err != nil {
+			return nil, errors.Wrap(err, "failed to decode header")
+		}
+	}
+
+	if v, ok := item["body"].(*types.AttributeValueMemberB); ok {
+		rec.Body = v.Value
+	}
+
+	return rec, nil
+}
+
+func unixAttribute(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
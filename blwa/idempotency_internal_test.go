@@ -0,0 +1,126 @@
+package blwa
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cockroachdb/errors"
+)
+
+// fakeDynamo implements DynamoDBItemAPI for a single table keyed by "pk". It only understands the
+// condition expression used by DynamoIdempotencyStore.
+type fakeDynamo struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeDynamo) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[in.Key["pk"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (f *fakeDynamo) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	pk := in.Item["pk"].(*types.AttributeValueMemberS).Value
+
+	if existing, ok := f.items[pk]; ok && in.ConditionExpression != nil {
+		expires, _ := strconv.ParseInt(existing["expires_at"].(*types.AttributeValueMemberN).Value, 10, 64)
+		now, _ := strconv.ParseInt(in.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value, 10, 64)
+
+		if expires >= now {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("exists"), Item: existing}
+		}
+	}
+
+	f.items[pk] = in.Item
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(f.items, in.Key["pk"].(*types.AttributeValueMemberS).Value)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoIdempotencyStore(client, "idempotency")
+
+	_, locked, err := store.Lock(ctx, "k1", "fp1", time.Hour)
+	if err != nil || !locked {
+		t.Fatalf("expected to lock, got locked=%v err=%v", locked, err)
+	}
+
+	existing, locked, err := store.Lock(ctx, "k1", "fp1", time.Hour)
+	if err != nil || locked {
+		t.Fatalf("expected existing lock, got locked=%v err=%v", locked, err)
+	}
+
+	if existing.Fingerprint != "fp1" || existing.Completed {
+		t.Errorf("expected in-flight record for fp1, got %+v", existing)
+	}
+
+	if err := store.Save(ctx, "k1", &bhttp.IdempotencyRecord{
+		Fingerprint: "fp1",
+		Completed:   true,
+		Status:      http.StatusCreated,
+		Header:      http.Header{"Location": {"/orders/1"}},
+		Body:        []byte("order 1"),
+	}, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	existing, _, err = store.Lock(ctx, "k1", "fp1", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !existing.Completed || existing.Status != http.StatusCreated || string(existing.Body) != "order 1" ||
+		existing.Header.Get("Location") != "/orders/1" {
+		t.Errorf("expected recorded response, got %+v", existing)
+	}
+
+	if err := store.Release(ctx, "k1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, locked, _ := store.Lock(ctx, "k1", "fp2", time.Hour); !locked {
+		t.Errorf("expected released key to be lockable")
+	}
+
+	client.items["k1"]["expires_at"] = unixAttribute(time.Now().Add(-time.Minute))
+	if _, locked, _ := store.Lock(ctx, "k1", "fp3", time.Hour); !locked {
+		t.Errorf("expected expired key to be lockable")
+	}
+}
+
+func TestDynamoIdempotencyStoreTooLarge(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoIdempotencyStore(client, "idempotency")
+
+	if _, locked, err := store.Lock(ctx, "k1", "fp1", time.Hour); err != nil || !locked {
+		t.Fatalf("expected to lock, got locked=%v err=%v", locked, err)
+	}
+
+	err := store.Save(ctx, "k1", &bhttp.IdempotencyRecord{
+		Fingerprint: "fp1",
+		Completed:   true,
+		Status:      http.StatusOK,
+		Body:        bytes.Repeat([]byte("x"), 400*1024),
+	}, time.Hour)
+	if !errors.Is(err, bhttp.ErrIdempotencyRecordTooLarge) {
+		t.Fatalf("expected record too large, got %v", err)
+	}
+
+	if client.items["k1"]["completed"].(*types.AttributeValueMemberBOOL).Value {
+		t.Errorf("expected the large response not to be recorded")
+	}
+}
@@ -1,9 +1,11 @@
 package blwa
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/advdv/bhttp"
 	"github.com/cockroachdb/errors"
 
 	"go.uber.org/zap"
@@ -155,6 +157,25 @@ func TestZapLogger(t *testing.T) {
 	})
 }
 
+func TestLog_RequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithLogger(context.Background(), zap.New(core))
+
+	Log(ctx).Info("without id")
+	Log(bhttp.WithRequestID(ctx, "req-1")).Info("with id")
+
+	entries := logs.TakeAll()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+	if _, ok := entries[0].ContextMap()["request_id"]; ok {
+		t.Errorf("expected no request_id field, got %v", entries[0].ContextMap())
+	}
+	if got := entries[1].ContextMap()["request_id"]; got != "req-1" {
+		t.Errorf("expected request_id=req-1, got %v", got)
+	}
+}
+
 func TestBaseEnvironment_LogLevel_Default(t *testing.T) {
 	t.Setenv("AWS_LWA_PORT", "8080")
 	t.Setenv("BW_SERVICE_NAME", "test")
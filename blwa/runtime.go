@@ -3,7 +3,9 @@ package blwa
 import (
 	"context"
 	"net/http"
+	"net/url"
 
+	"github.com/advdv/bhttp"
 	"github.com/carlmjohnson/requests"
 	"github.com/cockroachdb/errors"
 )
@@ -62,6 +64,19 @@ func (r *Runtime[E]) Reverse(name string, params ...string) (string, error) {
 	return r.mux.Reverse(name, params...)
 }
 
+// ReverseWith returns the URL for a named route with parameters by wildcard name and an
+// optional query string.
+func (r *Runtime[E]) ReverseWith(name string, params bhttp.Params, query url.Values) (string, error) {
+	return r.mux.ReverseWith(name, params, query)
+}
+
+// ReverseURL returns the absolute URL for a named route relative to base. Use [bhttp.BaseURL]
+// to derive the base from the incoming request, or configure it for links that are generated
+// outside of a request (e.g. in emails or webhooks).
+func (r *Runtime[E]) ReverseURL(base *url.URL, name string, params ...string) (string, error) {
+	return r.mux.ReverseURL(base, name, params...)
+}
+
 // Secret retrieves a secret value from AWS Secrets Manager.
 //
 // The secretID is the secret name or ARN to read from (required).
@@ -87,7 +102,8 @@ func (r *Runtime[E]) Secret(ctx context.Context, secretID string, jsonPath ...st
 
 // NewRequest returns a fresh [requests.Builder] pre-configured with the
 // instrumented HTTP transport. Each call returns a new builder, so there is
-// no risk of shared mutable state between requests.
+// no risk of shared mutable state between requests. Requests carry the
+// request id of the context that is passed to Fetch.
 //
 // Example:
 //
@@ -17,6 +17,11 @@ import (
 // ServerConfig holds optional configuration for the HTTP server.
 type ServerConfig struct {
 	HealthHandler func(http.ResponseWriter, *http.Request)
+	// DisableRequestID leaves out the [bhttp.RequestID] middleware, so error responses do not carry the id.
+	DisableRequestID bool
+	// DisableRouteSpans keeps the span names of the HTTP instrumentation instead of naming spans after the
+	// matched route.
+	DisableRouteSpans bool
 }
 
 // ServerParams holds the dependencies for creating an HTTP server.
@@ -38,8 +43,16 @@ func NewServer(params ServerParams, cfg ServerConfig) *http.Server {
 
 	params.Mux.Use(withRequestDep(d))
 	params.Mux.Use(withLWAContext())
+	// Identify requests by the id of the client, or else of the Lambda invocation.
+	if !cfg.DisableRequestID {
+		params.Mux.Use(bhttp.RequestID(bhttp.WithRequestIDGenerator(lwaRequestID)))
+	}
 	// Apply per-request deadline from Lambda context (takes precedence over server timeouts).
 	params.Mux.Use(WithRequestDeadline(DefaultDeadlineBuffer))
+	// Name spans after the matched route rather than the concrete request path.
+	if !cfg.DisableRouteSpans {
+		params.Mux.Use(withRouteSpan())
+	}
 
 	// Register the health check endpoint at the path specified by AWS_LWA_READINESS_CHECK_PATH.
 	// This endpoint is called by Lambda Web Adapter to determine if the app is ready.
@@ -0,0 +1,95 @@
+package blwa
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cockroachdb/errors"
+)
+
+// DynamoSessionStore is a [bhttp.SessionStore] that keeps sessions in a DynamoDB table, so that they are
+// shared by all Lambda instances. The table must have a string partition key named "pk". Enable time to
+// live on the "expires_at" attribute to have DynamoDB remove expired sessions; expired sessions that were
+// not removed yet are ignored.
+//
+// The client is typically registered with [WithAWSClient]:
+//
+//	blwa.WithFx(fx.Provide(func(c *dynamodb.Client, env Env) bhttp.SessionStore {
+//	    return blwa.NewDynamoSessionStore(c, env.SessionTable)
+//	})),
+type DynamoSessionStore struct {
+	client DynamoDBItemAPI
+	table  string
+}
+
+// NewDynamoSessionStore inits a store that keeps sessions in 'table'.
+func NewDynamoSessionStore(client DynamoDBItemAPI, table string) *DynamoSessionStore {
+	return &DynamoSessionStore{client: client, table: table}
+}
+
+// Load implements [bhttp.SessionStore].
+func (s *DynamoSessionStore) Load(ctx context.Context, token string) ([]byte, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: token}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session")
+	}
+
+	if v, ok := out.Item["expires_at"].(*types.AttributeValueMemberN); ok {
+		expires, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode session expiry")
+		}
+
+		if time.Now().Unix() > expires {
+			return nil, nil
+		}
+	}
+
+	data, _ := out.Item["data"].(*types.AttributeValueMemberB)
+	if data == nil {
+		return nil, nil
+	}
+
+	return data.Value, nil
+}
+
+// Save implements [bhttp.SessionStore].
+func (s *DynamoSessionStore) Save(ctx context.Context, token string, data []byte, ttl time.Duration) (string, error) {
+	if token == "" {
+		token = bhttp.NewSessionID()
+	}
+
+	if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"pk":         &types.AttributeValueMemberS{Value: token},
+			"data":       &types.AttributeValueMemberB{Value: data},
+			"expires_at": unixAttribute(time.Now().Add(ttl)),
+		},
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to put session")
+	}
+
+	return token, nil
+}
+
+// Delete implements [bhttp.SessionStore].
+func (s *DynamoSessionStore) Delete(ctx context.Context, token string) error {
+	if _, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: token}},
+	}); err != nil {
+		return errors.Wrap(err, "failed to delete session")
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package blwa
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestDynamoSessionStore(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoSessionStore(client, "sessions")
+
+	token, err := store.Save(ctx, "", []byte(`{"user":"u1"}`), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if token == "" {
+		t.Fatalf("expected a new session id")
+	}
+
+	data, err := store.Load(ctx, token)
+	if err != nil || string(data) != `{"user":"u1"}` {
+		t.Fatalf("expected stored data, got %q err=%v", data, err)
+	}
+
+	if again, _ := store.Save(ctx, token, []byte(`{}`), time.Hour); again != token {
+		t.Errorf("expected existing token to be kept, got %q", again)
+	}
+
+	if data, _ := store.Load(ctx, "other"); data != nil {
+		t.Errorf("expected no data for unknown session, got %q", data)
+	}
+
+	if err := store.Delete(ctx, token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data, _ := store.Load(ctx, token); data != nil {
+		t.Errorf("expected deleted session to be gone, got %q", data)
+	}
+
+	expired, _ := store.Save(ctx, "", []byte(`{}`), -time.Minute)
+	if data, _ := store.Load(ctx, expired); data != nil {
+		t.Errorf("expected expired session to be ignored, got %q", data)
+	}
+}
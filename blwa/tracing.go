@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/advdv/bhttp"
 	"github.com/aws-observability/aws-otel-go/exporters/xrayudp"
 	"github.com/cockroachdb/errors"
 	"go.opentelemetry.io/contrib/detectors/aws/lambda"
@@ -157,3 +158,24 @@ func withTracing(
 		)
 	}
 }
+
+// withRouteSpan names the current span after the matched route pattern and records the route as the
+// http.route attribute, so traces group by route instead of by concrete path. Named routes also get a
+// bhttp.route.name attribute.
+func withRouteSpan() bhttp.Middleware {
+	return func(next bhttp.BareHandler) bhttp.BareHandler {
+		return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+			if route := bhttp.MatchedRoute(r.Context()); route != nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + route.Host + route.Path)
+				span.SetAttributes(semconv.HTTPRoute(route.Path))
+
+				if route.Name != "" {
+					span.SetAttributes(attribute.String("bhttp.route.name", route.Name))
+				}
+			}
+
+			return next.ServeBareBHTTP(w, r)
+		})
+	}
+}
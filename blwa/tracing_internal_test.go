@@ -6,10 +6,12 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/advdv/bhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 )
@@ -327,3 +329,39 @@ func TestWithTracing(t *testing.T) {
 		}
 	})
 }
+
+func TestWithRouteSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mux := bhttp.NewServeMux()
+	mux.Use(withRouteSpan())
+	mux.HandleFunc("GET /items/{id}", func(context.Context, bhttp.ResponseWriter, *http.Request) error {
+		return nil
+	}, bhttp.Name("get-item"))
+
+	handler := withTracing(tp, propagation.TraceContext{}, "test-service")(mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if got := spans[0].Name(); got != "GET /items/{id}" {
+		t.Errorf("expected span name %q, got %q", "GET /items/{id}", got)
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	if attrs["http.route"] != "/items/{id}" {
+		t.Errorf("expected http.route %q, got %q", "/items/{id}", attrs["http.route"])
+	}
+
+	if attrs["bhttp.route.name"] != "get-item" {
+		t.Errorf("expected bhttp.route.name %q, got %q", "get-item", attrs["bhttp.route.name"])
+	}
+}
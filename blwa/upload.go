@@ -0,0 +1,82 @@
+package blwa
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/advdv/bhttp"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+)
+
+// S3ObjectAPI is the part of the S3 client that is used by [S3UploadSink].
+type S3ObjectAPI interface {
+	PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, opts ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3UploadSink is a [bhttp.UploadSink] that stores uploaded files as objects in an S3 bucket, under a
+// random key with the given prefix. The location of a file is its object key.
+//
+// Files are spooled to the temporary directory before they are put, since S3 needs to know the size of
+// an object upfront. On Lambda this is the ephemeral storage, which bounds the size of a single file.
+type S3UploadSink struct {
+	client S3ObjectAPI
+	bucket string
+	prefix string
+}
+
+// NewS3UploadSink inits a sink that stores files in 'bucket' with keys that start with 'prefix'.
+func NewS3UploadSink(client S3ObjectAPI, bucket, prefix string) *S3UploadSink {
+	return &S3UploadSink{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Store implements [bhttp.UploadSink].
+func (s *S3UploadSink) Store(ctx context.Context, file *bhttp.UploadedFile, content io.Reader) (string, error) {
+	spool, err := os.CreateTemp("", "blwa-upload-*")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create spool file")
+	}
+
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+
+	size, err := io.Copy(spool, content)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to spool file")
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", errors.Wrap(err, "failed to rewind spool file")
+	}
+
+	key := s.prefix + uuid.NewString()
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          spool,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(file.ContentType),
+	}); err != nil {
+		return "", errors.Wrapf(err, "failed to put object %q", key)
+	}
+
+	return key, nil
+}
+
+// Remove implements [bhttp.UploadSink].
+func (s *S3UploadSink) Remove(ctx context.Context, location string) error {
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(location),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to delete object %q", location)
+	}
+
+	return nil
+}
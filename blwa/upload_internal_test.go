@@ -0,0 +1,65 @@
+package blwa
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 implements S3ObjectAPI by keeping objects in memory.
+type fakeS3 struct {
+	objects      map[string]string
+	contentTypes map[string]string
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.objects[*in.Bucket+"/"+*in.Key] = string(data)
+	f.contentTypes[*in.Bucket+"/"+*in.Key] = *in.ContentType
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, *in.Bucket+"/"+*in.Key)
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestS3UploadSink(t *testing.T) {
+	client := &fakeS3{objects: map[string]string{}, contentTypes: map[string]string{}}
+	sink := NewS3UploadSink(client, "uploads", "incoming/")
+
+	key, err := sink.Store(context.Background(), &bhttp.UploadedFile{ContentType: "text/plain"}, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(key, "incoming/") {
+		t.Errorf("expected key with prefix incoming/, got %q", key)
+	}
+
+	if got := client.objects["uploads/"+key]; got != "hello" {
+		t.Errorf("expected stored object hello, got %q", got)
+	}
+
+	if got := client.contentTypes["uploads/"+key]; got != "text/plain" {
+		t.Errorf("expected content type text/plain, got %q", got)
+	}
+
+	if err := sink.Remove(context.Background(), key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(client.objects) != 0 {
+		t.Errorf("expected object to be removed, got %v", client.objects)
+	}
+}
@@ -0,0 +1,50 @@
+package bhttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// ctxKey is the key type for context values.
+type ctxKey int
+
+const (
+	ctxKeyRouteInfo ctxKey = iota
+	ctxKeyMountPrefix
+	ctxKeyJWTClaims
+	ctxKeyPrincipal
+	ctxKeyCSRF
+	ctxKeySession
+	ctxKeyRequestID
+)
+
+// withRouteInfo makes the matched route available to the handler and all of its middleware.
+func withRouteInfo(info *RouteInfo, next BareHandler) BareHandler {
+	return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+		ctx := context.WithValue(r.Context(), ctxKeyRouteInfo, info)
+		return next.ServeBareBHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withMountPrefix records that 'prefix' has been stripped from the request path, on top of any
+// prefix that was stripped by an outer mux.
+func withMountPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, ctxKeyMountPrefix, MountPrefix(ctx)+prefix)
+}
+
+// MatchedRoute returns the route that the mux matched for the request, or nil if the request did not
+// match any route (for example while rendering a "not found" error). Middleware registered through
+// [ServeMux.Use] can use it for metrics labels, authorization policies or span names. With nested
+// muxes the innermost matched route is returned. The returned value must not be modified.
+func MatchedRoute(ctx context.Context) *RouteInfo {
+	info, _ := ctx.Value(ctxKeyRouteInfo).(*RouteInfo)
+	return info
+}
+
+// MountPrefix returns the path prefix that has been stripped from the request path by (possibly nested)
+// mounts, or the empty string if the request was not routed through a mount. Middleware of a mounted
+// route still sees the original path, so it observes the prefix of outer mounts only.
+func MountPrefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(ctxKeyMountPrefix).(string)
+	return prefix
+}
@@ -0,0 +1,176 @@
+package bhttp
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// CORSConfig configures the [CORS] middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins that may make cross-origin requests. An entry is an exact origin
+	// such as "https://app.example.com", an origin with a wildcard subdomain such as
+	// "https://*.example.com", or "*" to allow any origin.
+	AllowedOrigins []string
+	// AllowOriginFunc is consulted for origins that AllowedOrigins does not list.
+	AllowOriginFunc func(origin string, r *http.Request) bool
+	// AllowedHeaders lists the request headers that preflight responses allow. When empty, the headers
+	// that the preflight request asks for are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers that scripts may read, besides the CORS-safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication. It cannot be combined with the
+	// "*" origin, or a wildcard pattern such as "https://*" that is not limited to the subdomains of a
+	// domain, since that would let every site make requests with the credentials of the user.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response, zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// CORS returns middleware that implements cross-origin resource sharing. Responses to allowed origins
+// carry the CORS headers, including error responses that are rendered after the response was reset.
+// Preflight requests are answered with the methods of the routes that are registered for the requested
+// path, or the error that the path would otherwise get, such as [CodeNotFound]. A route that matches
+// "OPTIONS" requests handles its own preflight requests. It panics when credentials are allowed for any
+// origin, or for a wildcard pattern that is not limited to the subdomains of a domain, such as
+// "https://*" or "https://*.com".
+func CORS(cfg CORSConfig) Middleware {
+	if cfg.AllowCredentials {
+		for _, allowed := range cfg.AllowedOrigins {
+			if allowed == "*" || !boundedWildcardOrigin(allowed) {
+				panic("bhttp: CORS cannot allow credentials for origin pattern " + allowed +
+					", list the allowed origins or the subdomains of a domain instead")
+			}
+		}
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			hdr := w.Header()
+			hdr.Add("Vary", "Origin")
+			PreserveHeaders(w, "Vary")
+
+			if !cfg.allowsOrigin(origin, r) {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			if slices.Contains(cfg.AllowedOrigins, "*") {
+				hdr.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				hdr.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if cfg.AllowCredentials {
+				hdr.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			PreserveHeaders(w, "Access-Control-Allow-Origin", "Access-Control-Allow-Credentials")
+
+			if isPreflight(r) && MatchedRoute(r.Context()) == nil {
+				return cfg.preflight(next, w, r)
+			}
+
+			if len(cfg.ExposedHeaders) > 0 {
+				hdr.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+				PreserveHeaders(w, "Access-Control-Expose-Headers")
+			}
+
+			return next.ServeBareBHTTP(w, r)
+		})
+	}
+}
+
+// isPreflight reports whether the request is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight answers a preflight request for a path that has no "OPTIONS" route. The mux answers such a
+// request with a [CodeMethodNotAllowed] error that lists the methods of the path, which become the
+// allowed methods. Any other outcome is returned as is.
+func (cfg CORSConfig) preflight(next BareHandler, w ResponseWriter, r *http.Request) error {
+	err := next.ServeBareBHTTP(w, r)
+
+	var berr *Error
+	if !errors.As(err, &berr) || berr.Code() != CodeMethodNotAllowed {
+		return err
+	}
+
+	hdr := w.Header()
+	hdr.Add("Vary", "Access-Control-Request-Method")
+	hdr.Add("Vary", "Access-Control-Request-Headers")
+	hdr.Set("Access-Control-Allow-Methods", berr.Header().Get("Allow"))
+
+	switch {
+	case len(cfg.AllowedHeaders) > 0:
+		hdr.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+	case r.Header.Get("Access-Control-Request-Headers") != "":
+		hdr.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+	}
+
+	if cfg.MaxAge > 0 {
+		hdr.Set("Access-Control-Max-Age", strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// allowsOrigin reports whether the origin may make cross-origin requests.
+func (cfg CORSConfig) allowsOrigin(origin string, r *http.Request) bool {
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || matchWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+
+	return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin, r)
+}
+
+// boundedWildcardOrigin reports whether the origin pattern, if it has a wildcard, only matches the
+// subdomains of a domain with at least two labels, such as "https://*.example.com".
+func boundedWildcardOrigin(pattern string) bool {
+	_, suffix, ok := strings.Cut(pattern, "*")
+	if !ok {
+		return true
+	}
+
+	domain, ok := strings.CutPrefix(suffix, ".")
+	if !ok {
+		return false
+	}
+
+	host, _, _ := strings.Cut(domain, ":")
+	labels := strings.Split(host, ".")
+
+	return len(labels) >= 2 && !slices.Contains(labels, "") && !strings.Contains(domain, "*")
+}
+
+// matchWildcardOrigin matches an origin against a pattern such as "https://*.example.com". The wildcard
+// matches one or more subdomain labels, but not the parent domain itself.
+func matchWildcardOrigin(pattern, origin string) bool {
+	prefix, suffix, ok := strings.Cut(strings.ToLower(pattern), "*")
+	if !ok {
+		return false
+	}
+
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) ||
+		!strings.HasSuffix(origin, suffix) {
+		return false
+	}
+
+	sub := origin[len(prefix) : len(origin)-len(suffix)]
+
+	return !strings.ContainsAny(sub, "/:@?#") && !strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".")
+}
@@ -0,0 +1,183 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.CORS(bhttp.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+		AllowOriginFunc:  func(origin string, _ *http.Request) bool { return strings.HasSuffix(origin, ".localhost") },
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	mux.HandleFunc("GET /items", noop)
+	mux.HandleFunc("POST /items", noop)
+	mux.HandleFunc("DELETE /items/{id}", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		w.Header().Set("X-Partial", "1")
+		_, _ = w.Write([]byte("partial"))
+
+		return bhttp.NewError(bhttp.CodeConflict, errors.New("item is in use"))
+	})
+	mux.HandleFunc("OPTIONS /custom", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		w.WriteHeader(http.StatusTeapot)
+		return nil
+	})
+
+	serve := func(method, path, origin string, hdr ...string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("allowed origins", func(t *testing.T) {
+		for _, origin := range []string{
+			"https://app.example.com", "https://pr-1.preview.example.com", "https://a.b.preview.example.com",
+			"http://dev.localhost",
+		} {
+			rec := serve(http.MethodGet, "/items", origin)
+			require.Equal(t, http.StatusOK, rec.Code, origin)
+			require.Equal(t, origin, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+			require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+			require.Equal(t, "X-Total-Count", rec.Header().Get("Access-Control-Expose-Headers"))
+			require.Equal(t, "Origin", rec.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("other origins", func(t *testing.T) {
+		for _, origin := range []string{
+			"https://evil.com", "https://preview.example.com", "http://x.preview.example.com",
+			"https://x.preview.example.com.evil.com", "https://evil.com/.preview.example.com",
+		} {
+			rec := serve(http.MethodGet, "/items", origin)
+			require.Equal(t, http.StatusOK, rec.Code, origin)
+			require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+			require.Equal(t, "Origin", rec.Header().Get("Vary"))
+		}
+
+		require.Empty(t, serve(http.MethodGet, "/items", "").Header().Get("Vary"), "same-origin request")
+	})
+
+	t.Run("preflight methods come from the routes", func(t *testing.T) {
+		rec := serve(http.MethodOptions, "/items", "https://app.example.com",
+			"Access-Control-Request-Method", "POST", "Access-Control-Request-Headers", "content-type")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Equal(t, "GET, HEAD, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "content-type", rec.Header().Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Empty(t, rec.Body.String())
+
+		rec = serve(http.MethodOptions, "/items/1", "https://app.example.com", "Access-Control-Request-Method", "DELETE")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Equal(t, "DELETE", rec.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("preflight for unknown paths", func(t *testing.T) {
+		rec := serve(http.MethodOptions, "/other", "https://app.example.com", "Access-Control-Request-Method", "GET")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("preflight for disallowed origins", func(t *testing.T) {
+		rec := serve(http.MethodOptions, "/items", "https://evil.com", "Access-Control-Request-Method", "POST")
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("options routes handle their own preflight", func(t *testing.T) {
+		rec := serve(http.MethodOptions, "/custom", "https://app.example.com", "Access-Control-Request-Method", "PUT")
+		require.Equal(t, http.StatusTeapot, rec.Code)
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("error responses keep cors headers", func(t *testing.T) {
+		rec := serve(http.MethodDelete, "/items/1", "https://app.example.com")
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Equal(t, "Conflict: item is in use\n", rec.Body.String())
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		require.Equal(t, "X-Total-Count", rec.Header().Get("Access-Control-Expose-Headers"))
+		require.Equal(t, "Origin", rec.Header().Get("Vary"))
+		require.Empty(t, rec.Header().Get("X-Partial"))
+	})
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.CORS(bhttp.CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Authorization"}}))
+	mux.HandleFunc("PUT /items/{id}", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil })
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/items/1", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, x-custom")
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "PUT", rec.Header().Get("Access-Control-Allow-Methods"))
+	require.Empty(t, rec.Header().Get("Access-Control-Max-Age"))
+
+	for _, pattern := range []string{
+		"*", "https://*", "http://*", "https://*.com", "https://*example.com", "https://*.example.*",
+	} {
+		require.Panics(t, func() {
+			bhttp.CORS(bhttp.CORSConfig{AllowedOrigins: []string{pattern}, AllowCredentials: true})
+		}, pattern)
+	}
+
+	require.NotPanics(t, func() {
+		bhttp.CORS(bhttp.CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com", "http://*.example.com:8080"},
+			AllowCredentials: true,
+		})
+		bhttp.CORS(bhttp.CORSConfig{AllowedOrigins: []string{"https://*"}})
+	})
+}
+
+func TestCORSNestedMount(t *testing.T) {
+	inner := bhttp.NewServeMux()
+	inner.HandleFunc("POST /items", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		w.Header().Set("X-Partial", "1")
+		return bhttp.NewError(bhttp.CodeBadRequest, errors.New("invalid item"))
+	})
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.CORS(bhttp.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	mux.MountStd("/api", inner)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "Origin", rec.Header().Get("Vary"))
+	require.Empty(t, rec.Header().Get("X-Partial"))
+}
@@ -0,0 +1,358 @@
+package bhttp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// CSRFMode selects how [CSRF] recognizes legitimate state-changing requests.
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit issues a random token in a signed cookie, and requires requests to send the same
+	// token in a header or form field. It needs no server-side state, but requires a key, see [WithCSRFKey].
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the token server-side in a [CSRFStore], usually the session, and requires
+	// requests to send it in a header or form field.
+	CSRFSynchronizer
+	// CSRFFetchMetadata rejects requests that the browser marks as cross-site through the "Sec-Fetch-Site"
+	// header, or, for browsers that do not send it, whose "Origin" does not match the host. Requests
+	// without either header do not come from a browser and are allowed. It needs no tokens.
+	CSRFFetchMetadata
+)
+
+// Defaults for the names under which [CSRF] exchanges tokens.
+const (
+	DefaultCSRFCookie = "csrf_token"
+	DefaultCSRFHeader = "X-CSRF-Token"
+	DefaultCSRFField  = "csrf_token"
+)
+
+// csrfTokenSize is the number of random bytes in a token.
+const csrfTokenSize = 32
+
+// minCSRFKeySize is the minimum size of the key that signs double-submit cookies.
+const minCSRFKeySize = 32
+
+// CSRFStore keeps the synchronizer token of a client server-side.
+type CSRFStore interface {
+	// CSRFToken returns the token of the client, or the empty string if it has none yet.
+	CSRFToken(r *http.Request) (string, error)
+	// SetCSRFToken stores a new token for the client.
+	SetCSRFToken(w ResponseWriter, r *http.Request, token string) error
+}
+
+// CSRFOption configures [CSRF].
+type CSRFOption func(*csrfOptions)
+
+type csrfOptions struct {
+	store   CSRFStore
+	key     []byte
+	binding func(r *http.Request) string
+	cookie  http.Cookie
+	header  string
+	field   string
+	origins []string
+}
+
+// WithCSRFKey sets the key that signs the cookie of the [CSRFDoubleSubmit] mode, which requires one of at
+// least 32 random bytes. The signature covers the binding of the request, see [WithCSRFBinding].
+func WithCSRFKey(key []byte) CSRFOption {
+	return func(o *csrfOptions) { o.key = key }
+}
+
+// WithCSRFBinding sets the function that returns a stable identifier of the session or user of a request
+// in the [CSRFDoubleSubmit] mode. The signature of the cookie covers it, so the cookie is only valid for
+// the session it was issued in. Without a binding, a sibling subdomain can plant a cookie that it obtained
+// for itself and defeat the check. Applications with [Sessions] can use [CSRFSynchronizer] with
+// [SessionCSRFStore] instead.
+func WithCSRFBinding(fn func(r *http.Request) string) CSRFOption {
+	return func(o *csrfOptions) { o.binding = fn }
+}
+
+// WithCSRFStore sets the store for the [CSRFSynchronizer] mode, which requires one.
+func WithCSRFStore(store CSRFStore) CSRFOption {
+	return func(o *csrfOptions) { o.store = store }
+}
+
+// WithCSRFCookie sets the template of the cookie that holds the token in the [CSRFDoubleSubmit] mode, its
+// value is ignored. It defaults to a secure, "SameSite=Lax" cookie named [DefaultCSRFCookie] with path
+// "/", readable by scripts so that they can send the token in a header.
+func WithCSRFCookie(cookie http.Cookie) CSRFOption {
+	return func(o *csrfOptions) { o.cookie = cookie }
+}
+
+// WithCSRFHeader sets the header that carries the token, it defaults to [DefaultCSRFHeader].
+func WithCSRFHeader(name string) CSRFOption {
+	return func(o *csrfOptions) { o.header = name }
+}
+
+// WithCSRFField sets the form field that carries the token, it defaults to [DefaultCSRFField].
+func WithCSRFField(name string) CSRFOption {
+	return func(o *csrfOptions) { o.field = name }
+}
+
+// WithTrustedOrigins lists other origins, such as "https://admin.example.com", whose requests are allowed
+// in the [CSRFFetchMetadata] mode.
+func WithTrustedOrigins(origins ...string) CSRFOption {
+	return func(o *csrfOptions) { o.origins = append(o.origins, origins...) }
+}
+
+// csrfExemptKey marks routes that are registered with [CSRFExempt].
+var csrfExemptKey = NewMetaKey[bool]("csrf-exempt") //nolint:gochecknoglobals
+
+// CSRFExempt excludes the route from [CSRF] protection, for example for a webhook that authenticates its
+// requests with a signature.
+func CSRFExempt() RouteOption {
+	return Meta(csrfExemptKey, true)
+}
+
+// CSRF returns middleware that protects cookie-authenticated routes against cross-site request forgery.
+// Requests with a safe method (GET, HEAD, OPTIONS and TRACE), and to routes registered with [CSRFExempt],
+// are not checked. Requests that match no route are not checked either, since they reach no handler that
+// could change state. Other requests that fail the check of the mode get a [CodeForbidden] error.
+//
+// In the token modes, handlers embed the token in forms with [CSRFField] or [CSRFToken]. The form field
+// is only read from "application/x-www-form-urlencoded" bodies; "multipart/form-data" requests, such as
+// uploads, must send the token in the header, so that the body is left for the handler to stream. It
+// panics when the [CSRFSynchronizer] mode has no store, or the [CSRFDoubleSubmit] mode no valid key.
+func CSRF(mode CSRFMode, opts ...CSRFOption) Middleware {
+	o := csrfOptions{
+		cookie: http.Cookie{
+			Name: DefaultCSRFCookie, Path: "/", Secure: true, SameSite: http.SameSiteLaxMode,
+		},
+		header: DefaultCSRFHeader,
+		field:  DefaultCSRFField,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch {
+	case mode == CSRFSynchronizer && o.store == nil:
+		panic("bhttp: the synchronizer CSRF mode requires a store")
+	case mode == CSRFDoubleSubmit && len(o.key) < minCSRFKeySize:
+		panic("bhttp: the double-submit CSRF mode requires a key of at least 32 bytes")
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			route := MatchedRoute(r.Context())
+			if route == nil {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			if exempt, _ := csrfExemptKey.Of(route); exempt {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			if mode == CSRFFetchMetadata {
+				if !isSafeMethod(r.Method) && !o.sameOrigin(r) {
+					return NewError(CodeForbidden, errors.New("cross-origin request rejected"))
+				}
+
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			token, err := o.token(w, r, mode)
+			if err != nil {
+				return err
+			}
+
+			if !isSafeMethod(r.Method) && !validCSRFToken(token, o.submitted(r)) {
+				return NewError(CodeForbidden, errors.New("CSRF token is missing or invalid"))
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKeyCSRF, &csrfState{token: token, field: o.field})
+
+			return next.ServeBareBHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// csrfState is what [CSRF] makes available to handlers.
+type csrfState struct {
+	token []byte
+	field string
+}
+
+// CSRFToken returns the CSRF token of the request for sending it back in a header or form field, or the
+// empty string if the request was not handled by the [CSRF] middleware in a token mode. Every call
+// returns a differently masked token, so that it cannot be recovered from compressed responses (BREACH).
+func CSRFToken(ctx context.Context) string {
+	state, _ := ctx.Value(ctxKeyCSRF).(*csrfState)
+	if state == nil {
+		return ""
+	}
+
+	return maskCSRFToken(state.token)
+}
+
+// CSRFField returns a hidden form input that carries the CSRF token of the request, for use in templates:
+//
+//	views.Render(w, http.StatusOK, "edit.html", map[string]any{"CSRF": bhttp.CSRFField(ctx)})
+//
+//	<form method="post">{{ .CSRF }} ...</form>
+func CSRFField(ctx context.Context) template.HTML {
+	state, _ := ctx.Value(ctxKeyCSRF).(*csrfState)
+	if state == nil {
+		return ""
+	}
+
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(state.field) + //nolint:gosec
+		`" value="` + maskCSRFToken(state.token) + `">`)
+}
+
+// token returns the token of the client, issuing a new one when it has none.
+func (o *csrfOptions) token(w ResponseWriter, r *http.Request, mode CSRFMode) ([]byte, error) {
+	var encoded string
+	if mode == CSRFSynchronizer {
+		var err error
+		if encoded, err = o.store.CSRFToken(r); err != nil {
+			return nil, errors.Wrap(err, "failed to load CSRF token")
+		}
+	} else if cookie, err := r.Cookie(o.cookie.Name); err == nil {
+		encoded = o.verifyCookie(r, cookie.Value)
+	}
+
+	if token, err := base64.RawURLEncoding.DecodeString(encoded); err == nil && len(token) == csrfTokenSize {
+		return token, nil
+	}
+
+	token := make([]byte, csrfTokenSize)
+	_, _ = rand.Read(token)
+	encoded = base64.RawURLEncoding.EncodeToString(token)
+
+	if mode == CSRFSynchronizer {
+		if err := o.store.SetCSRFToken(w, r, encoded); err != nil {
+			return nil, errors.Wrap(err, "failed to store CSRF token")
+		}
+
+		return token, nil
+	}
+
+	cookie := o.cookie
+	cookie.Value = encoded + "." + base64.RawURLEncoding.EncodeToString(o.signCookie(r, encoded))
+	http.SetCookie(w, &cookie)
+
+	return token, nil
+}
+
+// verifyCookie returns the encoded token of a double-submit cookie, or the empty string when its
+// signature does not match the token and the binding of the request.
+func (o *csrfOptions) verifyCookie(r *http.Request, value string) string {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return ""
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, o.signCookie(r, encoded)) {
+		return ""
+	}
+
+	return encoded
+}
+
+// signCookie signs the encoded token together with the cookie name and the binding of the request.
+func (o *csrfOptions) signCookie(r *http.Request, encoded string) []byte {
+	var binding string
+	if o.binding != nil {
+		binding = o.binding(r)
+	}
+
+	mac := hmac.New(sha256.New, o.key)
+	mac.Write([]byte(o.cookie.Name + "|" + binding + "|" + encoded))
+
+	return mac.Sum(nil)
+}
+
+// submitted returns the token that the request sent, from the header or else the form field of a
+// urlencoded form body. Multipart bodies are not parsed, since that would consume them.
+func (o *csrfOptions) submitted(r *http.Request) string {
+	if token := r.Header.Get(o.header); token != "" {
+		return token
+	}
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/x-www-form-urlencoded" {
+		return r.PostFormValue(o.field)
+	}
+
+	return ""
+}
+
+// sameOrigin reports whether the browser marks the request as same-origin or user initiated, or, for
+// browsers without fetch metadata, whether its origin is the host of the request or trusted.
+func (o *csrfOptions) sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return o.trusted(r.Header.Get("Origin"))
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // not a browser request.
+	}
+
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+
+	return o.trusted(origin)
+}
+
+// trusted reports whether the origin was listed with [WithTrustedOrigins].
+func (o *csrfOptions) trusted(origin string) bool {
+	return origin != "" && slices.ContainsFunc(o.origins, func(trusted string) bool {
+		return strings.EqualFold(trusted, origin)
+	})
+}
+
+// isSafeMethod reports whether the method is defined as safe, so it must not change state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// maskCSRFToken encodes the token XOR-ed with a random one-time pad, prefixed by the pad.
+func maskCSRFToken(token []byte) string {
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+	_, _ = rand.Read(pad)
+
+	subtle.XORBytes(masked[len(token):], token, pad)
+
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validCSRFToken reports whether the submitted (masked) token is the token of the client.
+func validCSRFToken(token []byte, submitted string) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil || len(masked) != 2*len(token) {
+		return false
+	}
+
+	unmasked := make([]byte, len(token))
+	subtle.XORBytes(unmasked, masked[len(token):], masked[:len(token)])
+
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
@@ -0,0 +1,246 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// csrfMux serves a form with the CSRF field and accepts posts to it.
+func csrfMux(mode bhttp.CSRFMode, opts ...bhttp.CSRFOption) *bhttp.ServeMux {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.CSRF(mode, opts...))
+	mux.HandleFunc("GET /form", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		_, err := fmt.Fprintf(w, "<form>%s</form>", bhttp.CSRFField(ctx))
+		return err
+	})
+	mux.HandleFunc("POST /form", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil })
+	mux.HandleFunc("POST /upload", func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		_, err := fmt.Fprint(w, r.FormValue("csrf_token"))
+		return err
+	})
+	mux.HandleFunc("POST /webhook", func(context.Context, bhttp.ResponseWriter, *http.Request) error {
+		return nil
+	}, bhttp.CSRFExempt())
+
+	return mux
+}
+
+var csrfFieldRe = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="([A-Za-z0-9_-]+)">`)
+
+var csrfKey = []byte("0123456789abcdef0123456789abcdef")
+
+// csrfSession binds double-submit cookies to the "X-Session" header of the tests.
+func csrfSession(r *http.Request) string { return r.Header.Get("X-Session") }
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	mux := csrfMux(bhttp.CSRFDoubleSubmit, bhttp.WithCSRFKey(csrfKey), bhttp.WithCSRFBinding(csrfSession))
+
+	issue := func(session string) (*http.Cookie, string) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/form", nil)
+		req.Header.Set("X-Session", session)
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+
+		field := csrfFieldRe.FindStringSubmatch(rec.Body.String())
+		require.Len(t, field, 2, rec.Body.String())
+
+		return cookies[0], field[1]
+	}
+
+	cookie, token := issue("alice")
+	require.Equal(t, bhttp.DefaultCSRFCookie, cookie.Name)
+	require.True(t, cookie.Secure)
+	require.False(t, cookie.HttpOnly)
+	require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	post := func(cookie *http.Cookie, form url.Values, header string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		if header != "" {
+			req.Header.Set(bhttp.DefaultCSRFHeader, header)
+		}
+
+		req.Header.Set("X-Session", "alice")
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("form field", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(cookie, url.Values{"csrf_token": {token}}, "").Code)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(cookie, nil, token).Code)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		rec := post(cookie, nil, "")
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		require.Equal(t, "Forbidden: CSRF token is missing or invalid\n", rec.Body.String())
+
+		require.Equal(t, http.StatusForbidden, post(nil, url.Values{"csrf_token": {token}}, "").Code)
+		require.Equal(t, http.StatusForbidden, post(cookie, nil, "garbage").Code)
+
+		other := &http.Cookie{Name: bhttp.DefaultCSRFCookie, Value: strings.Repeat("A", 43)}
+		require.Equal(t, http.StatusForbidden, post(other, nil, token).Code)
+	})
+
+	t.Run("cookie of another session", func(t *testing.T) {
+		planted, token := issue("mallory")
+		require.Equal(t, http.StatusForbidden, post(planted, nil, token).Code)
+		require.Equal(t, http.StatusForbidden, post(planted, url.Values{"csrf_token": {token}}, "").Code)
+	})
+
+	t.Run("unsigned cookie", func(t *testing.T) {
+		encoded, _, _ := strings.Cut(cookie.Value, ".")
+		unsigned := &http.Cookie{Name: bhttp.DefaultCSRFCookie, Value: encoded}
+		require.Equal(t, http.StatusForbidden, post(unsigned, nil, token).Code)
+	})
+
+	t.Run("multipart requires the header", func(t *testing.T) {
+		multipart := func(header string) *httptest.ResponseRecorder {
+			body := "--b\r\nContent-Disposition: form-data; name=\"csrf_token\"\r\n\r\n" + token + "\r\n--b--\r\n"
+			rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+			req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+			req.Header.Set("X-Session", "alice")
+			req.Header.Set(bhttp.DefaultCSRFHeader, header)
+			req.AddCookie(cookie)
+			mux.ServeHTTP(rec, req)
+
+			return rec
+		}
+
+		require.Equal(t, http.StatusForbidden, multipart("").Code)
+
+		rec := multipart(token)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, token, rec.Body.String(), "the handler reads the unconsumed body")
+	})
+
+	t.Run("requires a key", func(t *testing.T) {
+		require.Panics(t, func() { bhttp.CSRF(bhttp.CSRFDoubleSubmit) })
+		require.Panics(t, func() { bhttp.CSRF(bhttp.CSRFDoubleSubmit, bhttp.WithCSRFKey([]byte("short"))) })
+	})
+
+	t.Run("masked tokens differ", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/form", nil)
+		req.Header.Set("X-Session", "alice")
+		req.AddCookie(cookie)
+		mux.ServeHTTP(rec, req)
+
+		require.Empty(t, rec.Result().Cookies(), "existing token is kept")
+
+		again := csrfFieldRe.FindStringSubmatch(rec.Body.String())
+		require.NotEqual(t, token, again[1])
+		require.Equal(t, http.StatusOK, post(cookie, nil, again[1]).Code)
+	})
+
+	t.Run("exempt routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+// sessionCSRFStore keeps the synchronizer token of a single client.
+type sessionCSRFStore struct{ token string }
+
+func (s *sessionCSRFStore) CSRFToken(*http.Request) (string, error) { return s.token, nil }
+
+func (s *sessionCSRFStore) SetCSRFToken(_ bhttp.ResponseWriter, _ *http.Request, token string) error {
+	s.token = token
+	return nil
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	store := &sessionCSRFStore{}
+	mux := csrfMux(bhttp.CSRFSynchronizer, bhttp.WithCSRFStore(store), bhttp.WithCSRFHeader("X-Token"))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	require.Empty(t, rec.Result().Cookies())
+	require.NotEmpty(t, store.token)
+
+	field := csrfFieldRe.FindStringSubmatch(rec.Body.String())
+	require.Len(t, field, 2)
+
+	post := func(token string) int {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.Header.Set("X-Token", token)
+		mux.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, post(field[1]))
+	require.Equal(t, http.StatusForbidden, post(""))
+
+	store.token = ""
+	require.Equal(t, http.StatusForbidden, post(field[1]), "token was rotated")
+
+	require.PanicsWithValue(t, "bhttp: the synchronizer CSRF mode requires a store", func() {
+		bhttp.CSRF(bhttp.CSRFSynchronizer)
+	})
+}
+
+func TestCSRFFetchMetadata(t *testing.T) {
+	mux := csrfMux(bhttp.CSRFFetchMetadata, bhttp.WithTrustedOrigins("https://admin.example.com"))
+
+	post := func(path string, hdr ...string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://app.example.com"+path, nil)
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	for name, tc := range map[string]struct {
+		path string
+		hdr  []string
+		code int
+	}{
+		"same origin":          {"/form", []string{"Sec-Fetch-Site", "same-origin"}, http.StatusOK},
+		"user initiated":       {"/form", []string{"Sec-Fetch-Site", "none"}, http.StatusOK},
+		"cross site":           {"/form", []string{"Sec-Fetch-Site", "cross-site"}, http.StatusForbidden},
+		"same site":            {"/form", []string{"Sec-Fetch-Site", "same-site", "Origin", "https://x.example.com"}, http.StatusForbidden},
+		"trusted same site":    {"/form", []string{"Sec-Fetch-Site", "same-site", "Origin", "https://admin.example.com"}, http.StatusOK},
+		"origin matches host":  {"/form", []string{"Origin", "https://app.example.com"}, http.StatusOK},
+		"origin of other host": {"/form", []string{"Origin", "https://evil.com"}, http.StatusForbidden},
+		"trusted origin":       {"/form", []string{"Origin", "https://admin.example.com"}, http.StatusOK},
+		"no browser headers":   {"/form", nil, http.StatusOK},
+		"cross site to exempt": {"/webhook", []string{"Sec-Fetch-Site", "cross-site"}, http.StatusOK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := post(tc.path, tc.hdr...)
+			require.Equal(t, tc.code, rec.Code)
+
+			if tc.code == http.StatusForbidden {
+				require.Equal(t, "Forbidden: cross-origin request rejected\n", rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	require.Equal(t, "<form></form>", rec.Body.String(), "no tokens in this mode")
+}
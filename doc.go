@@ -18,7 +18,7 @@
 //	        return bhttp.NewError(bhttp.CodeNotFound, err)
 //	    }
 //	    return json.NewEncoder(w).Encode(item)
-//	}, "get-item")
+//	}, bhttp.Name("get-item"))
 //
 // # Handler Signature
 //
@@ -45,6 +45,7 @@
 // Key methods:
 //   - [ResponseWriter.Reset] clears the buffer and headers for a fresh response
 //   - [ResponseWriter.FlushBuffer] writes buffered content to the underlying writer
+//   - [ResponseWriter.FlushError] also flushes the underlying writer, sending the content to the client
 //   - [ResponseWriter.Free] returns the buffer to a pool (called automatically by the mux)
 //
 // Example of response replacement on error:
@@ -63,6 +64,72 @@
 //	    return nil
 //	}
 //
+// # JSON Handlers
+//
+// [JSON] adapts a function that takes and returns plain values into a
+// [Handler]. It decodes the request body, rejecting unknown fields and bodies
+// over the size limit, calls a "Validate() error" method of the input when
+// present, and encodes the result:
+//
+//	mux.Handle("POST /users", bhttp.JSON(func(ctx context.Context, in CreateUser) (User, error) {
+//	    return users.Create(ctx, in)
+//	}, bhttp.WithJSONStatus(http.StatusCreated)))
+//
+// Bad input is answered with [CodeUnsupportedMediaType], [CodeBadRequest],
+// [CodeRequestEntityTooLarge] or [CodeUnprocessableEntity], and errors returned
+// by the function are rendered like those of any other handler.
+//
+// # Server-Sent Events
+//
+// [SSE] returns a handler that streams events to the client, bypassing the
+// response buffer. Heartbeat comments keep idle connections open, the
+// "Last-Event-ID" of a reconnecting client is available to resume from, and the
+// stream ends cleanly when the client goes away. Errors returned before the
+// first event are rendered like those of any other handler:
+//
+//	mux.Handle("GET /jobs/{id}/events", bhttp.SSE(func(ctx context.Context, s *bhttp.EventStream) error {
+//	    for update := range jobs.Watch(ctx, s.LastEventID()) {
+//	        if err := s.Send(bhttp.Event{ID: update.ID, Type: "progress", Data: update.JSON}); err != nil {
+//	            return err
+//	        }
+//	    }
+//	    return nil
+//	}))
+//
+// # HTML Views
+//
+// [Views] renders html/template pages from an [io/fs.FS]. Each page is parsed
+// together with the layouts and partials, and [WithViewReverse] provides a "url"
+// function backed by [ServeMux.Reverse]. Because output goes to the response
+// buffer, a template that fails halfway returns an error and the partial page
+// is discarded:
+//
+//	views := bhttp.NewViews(templates, bhttp.WithViewReverse(mux), bhttp.WithViewReload(dev))
+//
+//	mux.HandleFunc("GET /admin/users", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+//	    return views.Render(w, http.StatusOK, "users/list.html", users)
+//	})
+//
+// # OpenAPI
+//
+// [ServeMux.OpenAPI] generates an OpenAPI 3.1 document from the registered
+// routes: paths and path parameters from the patterns, operation ids from the
+// route names, and schemas from the input and output types of [JSON] handlers
+// and typed routes. Error codes a [JSON] handler can return are declared with
+// [WithJSONErrors]. [ServeMux.HandleOpenAPI] serves the document, as YAML when the
+// path ends in ".yaml":
+//
+//	mux.HandleOpenAPI("GET /openapi.json", openapi.Info{Title: "Users", Version: "1.0.0"})
+//
+// The encoded document is deterministic, so a test can compare it with a
+// committed copy to surface API changes in code review:
+//
+//	data, err := mux.OpenAPI(info).YAML()
+//	require.NoError(t, err)
+//	golden, err := os.ReadFile("openapi.yaml")
+//	require.NoError(t, err)
+//	require.Equal(t, string(golden), string(data))
+//
 // # Error Handling
 //
 // When a handler returns an error, the buffer is automatically reset and an
@@ -78,6 +145,38 @@
 //	return bhttp.NewError(bhttp.CodeForbidden, errors.New("access denied"))
 //
 // All standard HTTP 4xx and 5xx status codes are available as [Code] constants.
+// Headers that must accompany an error response (such as "Retry-After") are set
+// through [Error.Header], since headers written to the [ResponseWriter] are
+// discarded together with the buffer.
+//
+// Path, query and header parameters can be parsed with accessors such as
+// [PathInt], [PathUUID], [QueryInt], [QueryBool], [QueryTime] and
+// [HeaderDuration]. They return a [CodeBadRequest] error naming the parameter,
+// so a parse failure is a single return:
+//
+//	id, err := bhttp.PathInt64(r, "id")
+//	if err != nil {
+//	    return err // 400 Bad Request: invalid path parameter "id": must be an integer
+//	}
+//
+// [Bind] fills a struct from tagged path, query, header and cookie values in one
+// call, and reports every invalid value in a single [CodeBadRequest] error that
+// wraps a [*BindError]:
+//
+//	var in struct {
+//	    Org    string   `path:"org"`
+//	    Page   int      `query:"page" default:"1"`
+//	    Tags   []string `query:"tag"`
+//	    Tenant *string  `header:"X-Tenant"`
+//	}
+//	if err := bhttp.Bind(r, &in); err != nil {
+//	    return err
+//	}
+//
+// Requests that match no registered pattern are answered with a [CodeNotFound]
+// error, or a [CodeMethodNotAllowed] error with an "Allow" header when the path
+// matches for other methods. These errors pass through the middleware registered
+// with [ServeMux.Use], so logging and custom error rendering apply to them too.
 //
 // # Middleware
 //
@@ -96,21 +195,263 @@
 //	mux := bhttp.NewServeMux()
 //	mux.Use(loggingMiddleware)
 //
+// [LimitRequests] is middleware that bounds request bodies, the counterpart of
+// the response buffer limit. It rejects bodies over a size limit, of a media
+// type that is not allowed, or of unknown length. Routes override the settings
+// they set with [Limits], or by name with [RouteLimits]:
+//
+//	mux.Use(bhttp.LimitRequests(bhttp.RequestLimits{
+//	    BodyLimit:    1 << 20,
+//	    ContentTypes: []string{"application/json"},
+//	}))
+//	mux.HandleFunc("PUT /avatar", putAvatar, bhttp.Limits(bhttp.RequestLimits{
+//	    BodyLimit:    10 << 20,
+//	    ContentTypes: []string{"image/*"},
+//	}))
+//
+// [Idempotency] is middleware that makes retries of requests with an
+// "Idempotency-Key" header safe: the key is locked in an [IdempotencyStore]
+// and the complete buffered response of the first request is recorded and
+// replayed for repeats. [MemoryIdempotencyStore] keeps keys in memory:
+//
+//	mux.Use(bhttp.Idempotency(bhttp.NewMemoryIdempotencyStore()))
+//
+// [LimitRate] is middleware that limits the rate of requests per client,
+// identified by IP address, API key or a custom [RateLimitKeyFunc]. Rules apply
+// to named routes, and routes listed together share a quota. Requests over the
+// limit get a [CodeTooManyRequests] error with a "Retry-After" header:
+//
+//	mux.Use(bhttp.LimitRate(bhttp.NewTokenBucketLimiter(),
+//	    bhttp.RateLimitRule{
+//	        Routes: []string{"create-order", "cancel-order"},
+//	        Rate:   bhttp.Rate{Requests: 10, Period: time.Minute},
+//	        Key:    bhttp.KeyByHeader("X-Api-Key"),
+//	    },
+//	    bhttp.RateLimitRule{
+//	        Rate: bhttp.Rate{Requests: 100, Period: time.Minute},
+//	        Key:  bhttp.KeyByForwardedIP(1), // behind API Gateway
+//	    },
+//	))
+//
+// [RequestID] is middleware that identifies every request with the id from the
+// "X-Request-Id" header, or a generated one. Handlers read it with
+// [RequestIDFrom], and it is echoed on responses and included in error
+// responses. A transport wrapped with [PropagateRequestID] sends it along with
+// outbound requests:
+//
+//	mux.Use(bhttp.RequestID())
+//	client := &http.Client{Transport: bhttp.PropagateRequestID(http.DefaultTransport)}
+//
+// [CORS] is middleware for cross-origin requests from exact origins, wildcard
+// subdomains or origins accepted by a function. Preflight requests are answered
+// with the methods of the routes registered for the path, and the CORS headers
+// are kept on error responses with [PreserveHeaders], so that scripts can read
+// them:
+//
+//	mux.Use(bhttp.CORS(bhttp.CORSConfig{
+//	    AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+//	    AllowCredentials: true,
+//	    MaxAge:           time.Hour,
+//	}))
+//
+// [CSRF] is middleware that protects cookie-authenticated form routes against
+// cross-site request forgery, with double-submit cookies, synchronizer tokens
+// kept in a [CSRFStore], or the "Sec-Fetch-Site" and "Origin" headers. Safe
+// methods and routes registered with [CSRFExempt] are not checked, rejected
+// requests get a [CodeForbidden] error. Forms embed the token with [CSRFField];
+// multipart forms, such as uploads, send it in the "X-CSRF-Token" header. The
+// double-submit cookie is signed, and bound to the session or user with
+// [WithCSRFBinding] so that a cookie planted by a sibling subdomain is rejected:
+//
+//	mux.Use(bhttp.CSRF(bhttp.CSRFDoubleSubmit,
+//	    bhttp.WithCSRFKey(csrfKey),
+//	    bhttp.WithCSRFBinding(func(r *http.Request) string { return userID(r) }),
+//	))
+//	mux.HandleFunc("POST /hooks/stripe", stripeHook, bhttp.CSRFExempt())
+//
+//	views.Render(w, http.StatusOK, "edit.html", map[string]any{"CSRF": bhttp.CSRFField(ctx)})
+//
+// [Sessions] is middleware that keeps a [Session] per client in a signed, and
+// optionally encrypted, cookie. The session data lives in a [SessionStore]: the
+// [CookieSessionStore] keeps it in the cookie itself, the [MemorySessionStore]
+// in memory. Sessions are loaded on first use with [SessionFrom], and saved only
+// when they were modified by a handler that succeeded. Keys are rotated by
+// adding a new key in front, and [SessionCSRFStore] keeps synchronizer tokens
+// in the session:
+//
+//	mux.Use(
+//	    bhttp.Sessions(bhttp.NewMemorySessionStore(), [][]byte{newKey, oldKey}),
+//	    bhttp.CSRF(bhttp.CSRFSynchronizer, bhttp.WithCSRFStore(bhttp.SessionCSRFStore())),
+//	)
+//
+//	sess, err := bhttp.SessionFrom(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//
+//	sess.Renew() // after logging in, to prevent session fixation.
+//	err = sess.Set("user", user.ID)
+//
+// [JWTAuth] is middleware that verifies bearer tokens signed with RS256, ES256,
+// HS256 or EdDSA and checks their "iss", "aud", "exp" and "nbf" claims. Keys
+// come from a [KeySet], such as a [JWKS] that fetches and caches the key set of
+// an identity provider. Failures get a [CodeUnauthorized] error with a
+// "WWW-Authenticate" challenge, handlers read the verified [Claims] with
+// [JWTClaims]:
+//
+//	jwks := bhttp.NewJWKS("https://idp.example.com/.well-known/jwks.json")
+//	mux.Use(bhttp.JWTAuth(jwks,
+//	    bhttp.WithIssuer("https://idp.example.com/"),
+//	    bhttp.WithAudience("orders-api"),
+//	    bhttp.WithPublicRoutes("list-products"),
+//	))
+//
+// File uploads are parsed with [ParseUpload], which streams the parts of a
+// multipart body to an [UploadSink] while enforcing size limits, a maximum part
+// count and allowed content types determined by sniffing. Limits that are left
+// zero get safe defaults, negative limits are disabled. [TempDirSink] stores
+// files on disk and is used when no sink is given:
+//
+//	upload, err := bhttp.ParseUpload(r, nil, bhttp.UploadLimits{
+//	    FileLimit:    10 << 20,
+//	    MaxParts:     5,
+//	    ContentTypes: []string{"image/png", "image/jpeg"},
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	defer upload.Remove(ctx)
+//
 // Middleware can inspect and transform errors, modify the request context,
 // or reset and replace responses entirely.
 //
+// # Authorization
+//
+// Route options declare a [Policy] when a route is registered: [Require] lists
+// scopes that the caller must all have, [RequireRole] roles of which it must have
+// one, and [Public] marks routes that need no authentication at all:
+//
+//	mux.HandleFunc("GET /health", health, bhttp.Public())
+//	mux.HandleFunc("DELETE /items/{id}", deleteItem, bhttp.Name("delete-item"), bhttp.Require("items:write"))
+//	mux.HandleFunc("GET /reports", reports, bhttp.RequireRole("auditor", "admin"))
+//
+// The mux enforces the policy after all middleware has run, against the
+// [Principal] that authentication middleware such as [JWTAuth] stored with
+// [WithPrincipal]. Callers without a principal get a [CodeUnauthorized] error,
+// callers without the scope or role a [CodeForbidden] error. The policy of every
+// route is part of [RouteInfo], so it can be reviewed:
+//
+//	for _, route := range mux.Routes() {
+//	    if route.Policy.Public {
+//	        fmt.Println("public:", route.Pattern)
+//	    }
+//	}
+//
+// # Route Metadata
+//
+// Besides a name and a policy, routes can carry typed metadata that middleware
+// reads back for the route that matched the request. A [MetaKey] declares the
+// type of the value, and [Meta] attaches a value at registration:
+//
+//	var Owner = bhttp.NewMetaKey[string]("owner")
+//
+//	mux.HandleFunc("GET /items", listItems, bhttp.Name("list-items"), bhttp.Meta(Owner, "team-catalog"))
+//
+//	func ownerMiddleware(next bhttp.BareHandler) bhttp.BareHandler {
+//	    return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+//	        if owner, ok := Owner.Value(r.Context()); ok {
+//	            w.Header().Set("X-Owner", owner)
+//	        }
+//	        return next.ServeBareBHTTP(w, r)
+//	    })
+//	}
+//
+// [MetaKey.Of] reads the value from a [RouteInfo], for example while listing
+// [ServeMux.Routes].
+//
+// # Wildcard Constraints
+//
+// A single-segment wildcard can be followed by a constraint that its value must
+// satisfy: one of the builtins "int", "uint", "alpha", "alnum" and "uuid", or a
+// regular expression that must match the whole (unescaped) value:
+//
+//	mux.HandleFunc("GET /posts/{id:int}", getPostByID)
+//	mux.HandleFunc("GET /posts/{slug:[a-z-]+}", getPostBySlug)
+//	mux.HandleFunc("GET /posts/{any}", getPostFallback)
+//
+// Patterns that only differ in their wildcard names and constraints do not
+// conflict. They are tried in registration order, with the unconstrained
+// pattern, if any, tried last. When no constraint is satisfied the request is
+// answered with a [CodeNotFound] error, also when a less specific pattern of a
+// different shape, such as "GET /posts/{rest...}", would match. Reversing a
+// constrained route checks the values against the constraints, and [RouteInfo]
+// reports them per segment.
+//
 // # Named Routes and URL Reversing
 //
 // Routes can be named for URL generation, avoiding hardcoded paths:
 //
-//	mux.HandleFunc("GET /users/{id}", getUser, "get-user")
-//	mux.HandleFunc("POST /users", createUser, "create-user")
+//	mux.HandleFunc("GET /users/{id}", getUser, bhttp.Name("get-user"))
+//	mux.HandleFunc("POST /users", createUser, bhttp.Name("create-user"))
 //
 //	// Generate URLs by name
 //	url, err := mux.Reverse("get-user", "123")  // returns "/users/123"
 //
+// The name is a [RouteOption]. Earlier versions took it as a trailing string
+// argument, which now fails to compile: replace a trailing "get-user" with
+// bhttp.Name("get-user").
+//
 // The [Reverser] component parses standard library route patterns and
-// substitutes path parameters in order.
+// substitutes path parameters in order. Values are escaped so that a value such
+// as "a/b?c" ends up unchanged in [net/http.Request.PathValue] instead of
+// producing a different url; [RejectSlashes] makes values containing a '/' an
+// error instead.
+//
+// [ServeMux.ReverseWith] takes the values by wildcard name instead, so reordering
+// the wildcards of a pattern cannot silently break links, and adds a query string:
+//
+//	loc, err := mux.ReverseWith("get-user", bhttp.Params{"id": "123"}, url.Values{"tab": {"posts"}})
+//	// returns "/users/123?tab=posts"
+//
+// Typed routes declare a pattern once, together with a struct that holds its
+// path parameters. The same value registers the handler, parses the parameters
+// and reverses urls; a mismatch between the pattern and the struct panics when
+// the route is declared:
+//
+//	type UserParams struct {
+//	    ID int64 `path:"id"`
+//	}
+//
+//	var GetUser = bhttp.Route[UserParams]("GET /users/{id}", bhttp.Name("get-user"))
+//
+//	GetUser.Handle(mux, func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request, p UserParams) error {
+//	    return json.NewEncoder(w).Encode(p.ID)
+//	})
+//
+//	loc, err := GetUser.Reverse(UserParams{ID: 123}) // returns "/users/123"
+//
+// Absolute URLs, for emails, webhooks or redirects, are built with
+// [ServeMux.ReverseURL]. The base URL carries the scheme, host and any path
+// prefix, such as an API Gateway stage. [BaseURL] derives it from the incoming
+// request, including the prefix of the mount the request was routed through.
+// Routes whose pattern has a host are built with that host and without the
+// path prefix of the base URL. Behind a proxy that sets the X-Forwarded-Proto and X-Forwarded-Host headers,
+// such as API Gateway, opt in to trusting them:
+//
+//	loc, err := mux.ReverseURL(bhttp.BaseURL(r, "/prod", bhttp.TrustForwardedHeaders()), "get-user", "123")
+//	// returns "https://api.example.com/prod/users/123"
+//
+// At request time the matched route, including its name, is available through
+// [MatchedRoute], for example to label metrics or name tracing spans:
+//
+//	func metrics(next bhttp.BareHandler) bhttp.BareHandler {
+//	    return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+//	        if route := bhttp.MatchedRoute(r.Context()); route != nil {
+//	            requests.WithLabelValues(route.Name).Inc()
+//	        }
+//	        return next.ServeBareBHTTP(w, r)
+//	    })
+//	}
 //
 // # Mounting
 //
@@ -130,7 +471,10 @@
 //	mux.MountStd("/debug", http.DefaultServeMux)
 //
 // Mount registers both the exact prefix and the subtree (e.g. /api and /api/)
-// on the underlying [http.ServeMux].
+// on the underlying [http.ServeMux]. Route options apply to both, so a mounted
+// subtree can declare a [Policy]:
+//
+//	mux.MountStd("/debug", http.DefaultServeMux, bhttp.RequireRole("admin"))
 //
 // # ServeMux
 //
@@ -142,7 +486,9 @@
 //   - [ServeMux.Use] registers middleware (must be called before Handle)
 //   - [ServeMux.Handle], [ServeMux.HandleFunc], and [ServeMux.HandleStd] register routes
 //   - [ServeMux.Mount], [ServeMux.MountFunc], [ServeMux.MountStd], and [ServeMux.MountBare] mount handlers under a prefix
-//   - [ServeMux.Reverse] generates URLs for named routes
+//   - [ServeMux.Reverse] and [ServeMux.ReverseURL] generate URLs for named routes
+//   - [ServeMux.Routes] lists every registered pattern, e.g. for debug pages or API surface tests
+//   - [ServeMux.OpenAPI] and [ServeMux.HandleOpenAPI] generate and serve an OpenAPI document
 //
 // # Standard library handlers and error ownership
 //
@@ -58,16 +58,33 @@ const (
 
 // Error describes an http error.
 type Error struct {
-	code Code
-	err  error
+	code   Code
+	err    error
+	header http.Header
 }
 
 // NewError inits a new error given the error code.
 func NewError(c Code, underlying error) *Error {
-	return &Error{c, underlying}
+	return &Error{code: c, err: underlying}
 }
 
 func (e *Error) Code() Code { return e.code }
+
+// Header returns the headers that are added to the response when the error is rendered, for example
+// "Allow" for [CodeMethodNotAllowed] or "Retry-After" for [CodeTooManyRequests]. Headers written to the
+// [ResponseWriter] are discarded when the buffer is reset, so this is the only way to send headers with
+// an error response.
+func (e *Error) Header() http.Header {
+	if e.header == nil {
+		e.header = make(http.Header)
+	}
+
+	return e.header
+}
+
+// Unwrap returns the underlying error, so that it can be inspected with [errors.Is] and [errors.As].
+func (e *Error) Unwrap() error { return e.err }
+
 func (e *Error) Error() string {
 	status := http.StatusText(int(e.Code()))
 	if status == "" {
@@ -1,6 +1,8 @@
 package bhttp_test
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/advdv/bhttp"
@@ -17,3 +19,20 @@ func TestErrorCode(t *testing.T) {
 	require.Equal(t, bhttp.CodeUnknown, bhttp.CodeOf(errors.New("bar")))
 	require.Equal(t, "Unknown: rab", bhttp.NewError(900, errors.New("rab")).Error())
 }
+
+func TestErrorHeader(t *testing.T) {
+	err := bhttp.NewError(bhttp.CodeTooManyRequests, errors.New("slow down"))
+	err.Header().Set("Retry-After", "10")
+
+	shdlr := bhttp.ToStd(bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, _ *http.Request) error {
+		w.Header().Set("X-Discarded", "1")
+		return err
+	}), -1, bhttp.NewTestLogger(t))
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)
+	shdlr.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "10", rec.Header().Get("Retry-After"))
+	require.Empty(t, rec.Header().Get("X-Discarded"))
+}
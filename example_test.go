@@ -25,7 +25,7 @@ func Example() {
 			"id":   id,
 			"name": "Example Item",
 		})
-	}, "get-item")
+	}, bhttp.Name("get-item"))
 
 	// Generate URL by route name
 	url, _ := mux.Reverse("get-item", "123")
@@ -173,11 +173,11 @@ func ExampleServeMux_Reverse() {
 
 	mux.HandleFunc("GET /users/{id}", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
 		return nil
-	}, "get-user")
+	}, bhttp.Name("get-user"))
 
 	mux.HandleFunc("GET /users/{userId}/posts/{postId}", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
 		return nil
-	}, "get-user-post")
+	}, bhttp.Name("get-user-post"))
 
 	url1, _ := mux.Reverse("get-user", "42")
 	url2, _ := mux.Reverse("get-user-post", "42", "101")
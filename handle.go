@@ -14,6 +14,7 @@ type ResponseWriter interface {
 	Reset()
 	Free()
 	FlushBuffer() error
+	FlushError() error
 }
 
 // Handler mirrors http.Handler but with a buffered response and error return.
@@ -57,35 +58,54 @@ func ToStd(h BareHandler, bufLimit int, logs Logger) http.Handler {
 		bresp := NewResponseWriter(resp, bufLimit)
 		defer bresp.Free()
 
-		if err := h.ServeBareBHTTP(bresp, req); err != nil {
+		if err := h.ServeBareBHTTP(bresp, req); err != nil && isFlushed(bresp) {
+			// The response was already (partially) sent, so it can no longer be replaced by an error
+			// response. This happens when a streaming handler fails halfway.
+			logs.LogUnhandledServeError(err)
+		} else if err != nil {
 			bresp.Reset() // reset the buffer
 
-			var berr *Error
+			var (
+				berr *Error
+				msg  string
+				code int
+			)
+
 			switch {
 			case errors.As(err, &berr):
-				http.Error(bresp, berr.Error(), int(berr.code))
+				for k, v := range berr.header {
+					bresp.Header()[k] = v
+				}
+
+				msg, code = berr.Error(), int(berr.code)
 			case errors.Is(err, context.DeadlineExceeded):
 				// Context deadline exceeded maps to 504 Gateway Timeout.
 				// This typically occurs when the request exceeds the Lambda timeout.
-				http.Error(bresp,
-					http.StatusText(http.StatusGatewayTimeout),
-					http.StatusGatewayTimeout)
+				msg, code = http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout
 			case errors.Is(err, ErrBufferFull):
 				// Response buffer exceeded the configured limit. This indicates the handler
 				// is generating a response larger than allowed, which is a server-side issue.
 				// 507 Insufficient Storage signals the server cannot store the representation.
-				http.Error(bresp,
-					"response body exceeds buffer limit",
-					http.StatusInsufficientStorage)
+				msg, code = "response body exceeds buffer limit", http.StatusInsufficientStorage
 			default:
 				logs.LogUnhandledServeError(err)
 
 				// Else, we assume a server error don't want the client to end up with a white screen so
 				// we render a 500 error with the standard text.
-				http.Error(bresp,
-					http.StatusText(http.StatusInternalServerError),
-					http.StatusInternalServerError)
+				msg, code = http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError
+			}
+
+			// include the request id, so that clients can refer to the failure when reporting it. It is
+			// carried by the error when the [RequestID] middleware runs inside this handler, and by the
+			// context when it ran before, for example when this handler is mounted.
+			var rerr *requestIDError
+			if errors.As(err, &rerr) {
+				msg += " (request id: " + rerr.id + ")"
+			} else if id := RequestIDFrom(req.Context()); id != "" {
+				msg += " (request id: " + id + ")"
 			}
+
+			http.Error(bresp, msg, code)
 		}
 
 		if err := bresp.FlushBuffer(); err != nil {
@@ -93,3 +113,10 @@ func ToStd(h BareHandler, bufLimit int, logs Logger) http.Handler {
 		}
 	})
 }
+
+// isFlushed reports whether part of the response has already been sent to the client.
+func isFlushed(w ResponseWriter) bool {
+	f, ok := w.(interface{ flushed() bool })
+
+	return ok && f.flushed()
+}
@@ -0,0 +1,305 @@
+package bhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultIdempotencyTTL is how long an idempotency key is remembered, unless configured otherwise with
+// [WithIdempotencyTTL].
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyBodyLimit is the maximum size of a request body that [Idempotency] reads to
+// fingerprint the request, unless configured otherwise with [WithIdempotencyBodyLimit].
+const DefaultIdempotencyBodyLimit = 1 << 20
+
+// ErrIdempotencyRecordTooLarge is returned by an [IdempotencyStore] that cannot record a response because
+// it is too large. The key is then released without logging an error, so a retry executes the request
+// again.
+var ErrIdempotencyRecordTooLarge = errors.New("idempotency record too large")
+
+// IdempotencyRecord is what an [IdempotencyStore] keeps for an idempotency key.
+type IdempotencyRecord struct {
+	Fingerprint string      // identifies the request the key was first used for.
+	Completed   bool        // whether the response below is recorded, false while the request is in flight.
+	Status      int         // status code of the recorded response.
+	Header      http.Header // headers that the handler set on the recorded response.
+	Body        []byte      // body of the recorded response.
+}
+
+// IdempotencyStore keeps track of idempotency keys. Implementations must be safe for concurrent use, and
+// Lock must be atomic across all instances that share the store.
+type IdempotencyStore interface {
+	// Lock claims 'key' for the request with 'fingerprint' until 'ttl' passed. When the key is already
+	// claimed it returns the existing record and false.
+	Lock(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Save records the response for a key that was locked before, completing it.
+	Save(ctx context.Context, key string, rec *IdempotencyRecord, ttl time.Duration) error
+	// Release removes the claim on a key that was locked before, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOption configures the middleware returned by [Idempotency].
+type IdempotencyOption func(*idempotencyOptions)
+
+type idempotencyOptions struct {
+	ttl       time.Duration
+	bodyLimit int64
+	logs      Logger
+}
+
+// WithIdempotencyTTL sets how long keys, and the responses recorded for them, are remembered. It
+// defaults to [DefaultIdempotencyTTL].
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.ttl = ttl }
+}
+
+// WithIdempotencyBodyLimit sets the maximum size of the request body that is read to fingerprint a
+// request with an idempotency key. It defaults to [DefaultIdempotencyBodyLimit], and the body limit that
+// a route declares with [Limits] takes precedence.
+func WithIdempotencyBodyLimit(n int64) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.bodyLimit = n }
+}
+
+// WithIdempotencyLogger sets the logger that reports responses that could not be recorded. It defaults to
+// the standard library's default logger.
+func WithIdempotencyLogger(logs Logger) IdempotencyOption {
+	return func(o *idempotencyOptions) { o.logs = logs }
+}
+
+// Idempotency returns middleware that makes requests with an "Idempotency-Key" header safe to retry. The
+// first request with a key locks it in 'store' and, when it succeeds, the complete buffered response is
+// recorded. Later requests with the key get the recorded response replayed, marked with an
+// "Idempotent-Replayed" header, without running the handler again. Only the headers that the handler set
+// are recorded, so headers of middleware that runs before this one, such as [RequestID] or [CORS], are
+// those of the replaying request.
+//
+// A key that is reused for a different request (method, url or body) is answered with
+// [CodeUnprocessableEntity], a key whose first request is still in flight with [CodeConflict], and a body
+// over the limit with [CodeRequestEntityTooLarge]. When the handler returns an error, or streams its
+// response, the key is released so the request can be retried. When recording the response fails, the
+// failure is logged and the key is released as well, so a retry executes the request again rather than
+// getting a conflict until the key expires. Requests without the header pass through.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) Middleware {
+	o := idempotencyOptions{ttl: DefaultIdempotencyTTL, bodyLimit: DefaultIdempotencyBodyLimit}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.logs == nil {
+		o.logs = NewStdLogger(log.Default())
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			buf, ok := w.(*ResponseBuffer)
+			if !ok {
+				return errors.Newf("idempotency requires a *ResponseBuffer, got: %T", w)
+			}
+
+			limit := o.bodyLimit
+			if limits, ok := limitsKey.Of(MatchedRoute(r.Context())); ok && limits.BodyLimit > 0 {
+				limit = limits.BodyLimit
+			}
+
+			r, fingerprint, err := requestFingerprint(r, limit)
+			if err != nil {
+				return err
+			}
+
+			existing, locked, err := store.Lock(r.Context(), key, fingerprint, o.ttl)
+			if err != nil {
+				return errors.Wrap(err, "failed to lock idempotency key")
+			}
+
+			if !locked {
+				return replayIdempotent(w, key, fingerprint, existing)
+			}
+
+			// headers set by middleware outside this one, such as a request id, are set again on a replay.
+			outer := buf.BufferedHeader().Clone()
+
+			ctx := context.WithoutCancel(r.Context())
+			if err := next.ServeBareBHTTP(w, r); err != nil || buf.flushed() {
+				if rerr := store.Release(ctx, key); rerr != nil {
+					return errors.Join(err, errors.Wrap(rerr, "failed to release idempotency key"))
+				}
+
+				return err
+			}
+
+			if err := store.Save(ctx, key, &IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      buf.Status(),
+				Header:      changedHeader(outer, buf.BufferedHeader()),
+				Body:        bytes.Clone(buf.BufferedBody()),
+			}, o.ttl); err != nil {
+				// the response is still sent, since the request was executed.
+				if !errors.Is(err, ErrIdempotencyRecordTooLarge) {
+					o.logs.LogUnhandledServeError(errors.Wrapf(err, "failed to record response for idempotency key %q", key))
+				}
+
+				if rerr := store.Release(ctx, key); rerr != nil {
+					o.logs.LogUnhandledServeError(errors.Wrapf(rerr, "failed to release idempotency key %q", key))
+				}
+			}
+
+			return nil
+		})
+	}
+}
+
+// replayIdempotent answers a request whose key was locked before.
+func replayIdempotent(w ResponseWriter, key, fingerprint string, existing *IdempotencyRecord) error {
+	switch {
+	case existing.Fingerprint != fingerprint:
+		return NewError(CodeUnprocessableEntity,
+			errors.Newf("idempotency key %q was used for a different request", key))
+	case !existing.Completed:
+		return NewError(CodeConflict, errors.Newf("a request with idempotency key %q is in progress", key))
+	}
+
+	for k, v := range existing.Header {
+		w.Header()[k] = v
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.Status)
+
+	_, err := w.Write(existing.Body)
+
+	return errors.Wrap(err, "failed to write replayed response")
+}
+
+// changedHeader returns a copy of the headers in 'after' that were added or changed since 'before'.
+func changedHeader(before, after http.Header) http.Header {
+	changed := http.Header{}
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			changed[k] = slices.Clone(v)
+		}
+	}
+
+	return changed
+}
+
+// requestFingerprint hashes the method, url and body of the request, reading at most 'limit' bytes of the
+// body. It returns a copy of the request from which the handler can still read the body.
+func requestFingerprint(r *http.Request, limit int64) (*http.Request, string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	if r.ContentLength > limit {
+		return nil, "", bodyTooLargeError(limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return nil, "", bodyTooLargeError(maxErr.Limit)
+	case err != nil:
+		return nil, "", NewError(CodeBadRequest, errors.Wrap(err, "failed to read request body"))
+	case int64(len(body)) > limit:
+		return nil, "", bodyTooLargeError(limit)
+	}
+
+	hash.Write(body)
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Body = io.NopCloser(bytes.NewReader(body))
+
+	return r2, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// MemoryIdempotencyStore is an [IdempotencyStore] that keeps keys in memory. It suits tests and
+// single-instance deployments, since keys are not shared between instances. Expired keys are removed
+// periodically while keys are locked.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryIdempotencyRecord
+	nextSweep time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	*IdempotencyRecord
+
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore inits an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]memoryIdempotencyRecord{}}
+}
+
+// Lock implements [IdempotencyStore].
+func (s *MemoryIdempotencyStore) Lock(
+	_ context.Context, key, fingerprint string, ttl time.Duration,
+) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, rec := range s.records {
+			if !now.Before(rec.expires) {
+				delete(s.records, k)
+			}
+		}
+
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	if existing, ok := s.records[key]; ok && now.Before(existing.expires) {
+		return existing.IdempotencyRecord, false, nil
+	}
+
+	s.records[key] = memoryIdempotencyRecord{
+		IdempotencyRecord: &IdempotencyRecord{Fingerprint: fingerprint},
+		expires:           now.Add(ttl),
+	}
+
+	return nil, true, nil
+}
+
+// Save implements [IdempotencyStore].
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, rec *IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = memoryIdempotencyRecord{IdempotencyRecord: rec, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+// Release implements [IdempotencyStore].
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
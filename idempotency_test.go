@@ -0,0 +1,248 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int64
+
+	store := bhttp.NewMemoryIdempotencyStore()
+	release, entered := make(chan struct{}), make(chan struct{}, 1)
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.Idempotency(store))
+	mux.HandleFunc("POST /orders", func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		n := calls.Add(1)
+
+		switch r.URL.Query().Get("mode") {
+		case "fail":
+			return errors.New("payment provider unavailable")
+		case "slow":
+			entered <- struct{}{}
+			<-release
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/orders/%d", n))
+		w.WriteHeader(http.StatusCreated)
+		_, err := fmt.Fprintf(w, "order %d", n)
+
+		return err
+	})
+
+	post := func(key, query, body string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders"+query, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("replays the recorded response", func(t *testing.T) {
+		first := post("k1", "", `{"amount":10}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		require.Equal(t, "order 1", first.Body.String())
+
+		second := post("k1", "", `{"amount":10}`)
+		require.Equal(t, http.StatusCreated, second.Code)
+		require.Equal(t, "order 1", second.Body.String())
+		require.Equal(t, "/orders/1", second.Header().Get("Location"))
+		require.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+		require.Equal(t, int64(1), calls.Load())
+	})
+
+	t.Run("rejects a different request with the same key", func(t *testing.T) {
+		rec := post("k1", "", `{"amount":99}`)
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		require.Equal(t, "Unprocessable Entity: idempotency key \"k1\" was used for a different request\n", rec.Body.String())
+	})
+
+	t.Run("releases the key when the handler fails", func(t *testing.T) {
+		require.Equal(t, http.StatusInternalServerError, post("k2", "?mode=fail", "").Code)
+		require.Equal(t, http.StatusInternalServerError, post("k2", "?mode=fail", "").Code)
+		require.Equal(t, int64(3), calls.Load())
+	})
+
+	t.Run("rejects duplicates while in flight", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- post("k3", "?mode=slow", "") }()
+
+		<-entered
+		require.Equal(t, http.StatusConflict, post("k3", "?mode=slow", "").Code)
+
+		close(release)
+		require.Equal(t, http.StatusCreated, (<-done).Code)
+	})
+
+	t.Run("passes through without a key", func(t *testing.T) {
+		before := calls.Load()
+		post("", "", "")
+		post("", "", "")
+		require.Equal(t, before+2, calls.Load())
+	})
+}
+
+func TestIdempotencyOuterHeaders(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(
+		bhttp.RequestID(),
+		bhttp.CORS(bhttp.CORSConfig{AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"}}),
+		bhttp.Idempotency(bhttp.NewMemoryIdempotencyStore()))
+	mux.HandleFunc("POST /orders", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+
+		return nil
+	})
+
+	post := func(id, origin string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set("Idempotency-Key", "k1")
+		req.Header.Set(bhttp.DefaultRequestIDHeader, id)
+		req.Header.Set("Origin", origin)
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	first := post("id-1", "https://a.example.com")
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Equal(t, "id-1", first.Header().Get(bhttp.DefaultRequestIDHeader))
+	require.Equal(t, "https://a.example.com", first.Header().Get("Access-Control-Allow-Origin"))
+
+	second := post("id-2", "https://b.example.com")
+	require.Equal(t, http.StatusCreated, second.Code)
+	require.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	require.Equal(t, "/orders/1", second.Header().Get("Location"))
+	require.Equal(t, []string{"id-2"}, second.Header().Values(bhttp.DefaultRequestIDHeader))
+	require.Equal(t, []string{"https://b.example.com"}, second.Header().Values("Access-Control-Allow-Origin"))
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	ctx, store := context.Background(), bhttp.NewMemoryIdempotencyStore()
+
+	_, locked, err := store.Lock(ctx, "k", "fp", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	existing, locked, err := store.Lock(ctx, "k", "fp", time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, locked)
+	require.Equal(t, "fp", existing.Fingerprint)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, locked, err = store.Lock(ctx, "k", "fp", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, locked)
+}
+
+// failingIdempotencyStore fails to record responses.
+type failingIdempotencyStore struct {
+	*bhttp.MemoryIdempotencyStore
+
+	err error
+}
+
+func (s failingIdempotencyStore) Save(context.Context, string, *bhttp.IdempotencyRecord, time.Duration) error {
+	return s.err
+}
+
+func TestIdempotencySaveFailure(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		err     error
+		wantLog int64
+	}{
+		{"logs and releases", errors.New("store unavailable"), 1},
+		{"releases too large", errors.Wrap(bhttp.ErrIdempotencyRecordTooLarge, "too big"), 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int64
+
+			logs := bhttp.NewTestLogger(t)
+			store := failingIdempotencyStore{bhttp.NewMemoryIdempotencyStore(), test.err}
+
+			mux := bhttp.NewServeMux()
+			mux.Use(bhttp.Idempotency(store, bhttp.WithIdempotencyLogger(logs)))
+			mux.HandleFunc("POST /orders", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+				_, err := fmt.Fprintf(w, "order %d", calls.Add(1))
+
+				return err
+			})
+
+			for i := range 2 {
+				rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil)
+				req.Header.Set("Idempotency-Key", "k1")
+				mux.ServeHTTP(rec, req)
+
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, fmt.Sprintf("order %d", i+1), rec.Body.String())
+			}
+
+			require.Equal(t, int64(2), calls.Load())
+			require.Equal(t, 2*test.wantLog, logs.NumLogUnhandledServeError)
+		})
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.Idempotency(bhttp.NewMemoryIdempotencyStore(), bhttp.WithIdempotencyBodyLimit(8)))
+
+	var seen *http.Request
+
+	echo := func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		seen = r
+
+		_, err := io.Copy(w, r.Body)
+
+		return err
+	}
+	mux.HandleFunc("POST /echo", echo)
+	mux.HandleFunc("POST /large", echo, bhttp.Limits(bhttp.RequestLimits{BodyLimit: 16}))
+
+	post := func(path, body string, chunked bool) (*httptest.ResponseRecorder, *http.Request) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", path+body)
+
+		if chunked {
+			req.ContentLength = -1
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec, req
+	}
+
+	rec, req := post("/echo", "12345678", false)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "12345678", rec.Body.String())
+	require.NotSame(t, req, seen)
+
+	rec, _ = post("/echo", "123456789", false)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec, _ = post("/echo", "123456789", true)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Equal(t, "Request Entity Too Large: request body exceeds 8 bytes\n", rec.Body.String())
+
+	rec, _ = post("/large", "123456789", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,147 @@
+package httppattern
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Constraint restricts the values that a wildcard matches. It is declared in a pattern as "{name:expr}"
+// where 'expr' is one of the built-in constraints or a regular expression that must match the whole value.
+type Constraint struct {
+	expr  string
+	match func(s string) bool
+}
+
+// builtinConstraints are the named constraints that take precedence over regular expressions.
+var builtinConstraints = map[string]func(s string) bool{ //nolint:gochecknoglobals
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+}
+
+// NewConstraint parses 'expr' as a built-in constraint (int, uint, alpha, alnum or uuid), or else as a
+// regular expression that must match the whole value.
+func NewConstraint(expr string) (*Constraint, error) {
+	if expr == "" {
+		return nil, errors.Newf("invalid constraint %q: expression is empty", expr)
+	}
+
+	if match, ok := builtinConstraints[expr]; ok {
+		return &Constraint{expr: expr, match: match}, nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid constraint %q", expr)
+	}
+
+	return &Constraint{expr: expr, match: re.MatchString}, nil
+}
+
+// String returns the constraint expression as it appeared in the pattern.
+func (c *Constraint) String() string { return c.expr }
+
+// Match reports whether the (unescaped) wildcard value 's' satisfies the constraint.
+func (c *Constraint) Match(s string) bool { return c.match(s) }
+
+// stripConstraints removes the constraints from the wildcards in 's' so that the result can be parsed
+// by the standard library. It returns the constraints by wildcard name.
+func stripConstraints(s string) (string, map[string]*Constraint, error) {
+	if !strings.Contains(s, ":") {
+		return s, nil, nil // fast path: no pattern without a ':' can have constraints.
+	}
+
+	var (
+		res         strings.Builder
+		constraints map[string]*Constraint
+	)
+
+	for i := 0; i < len(s); {
+		// wildcards must start a path segment, anything else is copied verbatim.
+		if s[i] != '{' || i == 0 || s[i-1] != '/' {
+			res.WriteByte(s[i])
+			i++
+
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '/')
+		if end < 0 {
+			end = len(s) - i
+		}
+
+		seg := s[i : i+end]
+		i += end
+
+		name, expr, found := strings.Cut(seg[1:], ":")
+		if !found || !strings.HasSuffix(expr, "}") {
+			res.WriteString(seg)
+			continue
+		}
+
+		expr = strings.TrimSuffix(expr, "}")
+		if strings.HasSuffix(name, "...") || strings.HasSuffix(expr, "...") {
+			return "", nil, errors.Newf("constraints are not supported on %q wildcards", "...")
+		}
+
+		c, err := NewConstraint(expr)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if constraints == nil {
+			constraints = make(map[string]*Constraint)
+		}
+
+		constraints[name] = c
+
+		res.WriteString("{" + name + "}")
+	}
+
+	return res.String(), constraints, nil
+}
+
+func isInt(s string) bool {
+	return isUint(strings.TrimPrefix(s, "-"))
+}
+
+func isUint(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) < 0
+}
+
+func isAlpha(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z')
+	}) < 0
+}
+
+func isAlnum(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	}) < 0
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 { //nolint:mnd
+		return false
+	}
+
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23: //nolint:mnd
+			if r != '-' {
+				return false
+			}
+		default:
+			if (r < '0' || r > '9') && (r < 'a' || r > 'f') && (r < 'A' || r > 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,112 @@
+package httppattern_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp/internal/httppattern"
+)
+
+func TestConstraintMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		expr string
+		in   string
+		want bool
+	}{
+		{"int", "123", true},
+		{"int", "-123", true},
+		{"int", "", false},
+		{"int", "-", false},
+		{"int", "12a", false},
+		{"uint", "123", true},
+		{"uint", "-1", false},
+		{"alpha", "abcXYZ", true},
+		{"alpha", "abc1", false},
+		{"alnum", "abc1", true},
+		{"alnum", "abc-1", false},
+		{"uuid", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+		{"uuid", "6BA7B810-9DAD-11D1-80B4-00C04FD430C8", true},
+		{"uuid", "6ba7b8109dad11d180b400c04fd430c8", false},
+		{"[a-z-]+", "hello-world", true},
+		{"[a-z-]+", "Hello", false},
+		{"a|b", "a", true},
+		{"a|b", "ab", false},
+	} {
+		con, err := httppattern.NewConstraint(test.expr)
+		if err != nil {
+			t.Fatalf("%q: got: %v", test.expr, err)
+		}
+
+		if got := con.Match(test.in); got != test.want {
+			t.Errorf("%q.Match(%q): got %v, want %v", test.expr, test.in, got, test.want)
+		}
+	}
+}
+
+func TestParsePatternConstraints(t *testing.T) {
+	t.Parallel()
+
+	pat, err := httppattern.ParsePattern("GET /posts/{id:int}/{slug:[a-z-]+}/{rest...}")
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if got, want := pat.String(), "GET /posts/{id:int}/{slug:[a-z-]+}/{rest...}"; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+
+	if got, want := pat.StdString(), "GET /posts/{id}/{slug}/{rest...}"; got != want {
+		t.Errorf("StdString: got %q, want %q", got, want)
+	}
+
+	if got, want := pat.Path(), "/posts/{id:int}/{slug:[a-z-]+}/{rest...}"; got != want {
+		t.Errorf("Path: got %q, want %q", got, want)
+	}
+
+	if con := pat.Constraint("id"); con == nil || con.String() != "int" {
+		t.Errorf("Constraint(id): got %v", con)
+	}
+
+	if con := pat.Constraint("rest"); con != nil {
+		t.Errorf("Constraint(rest): got %v, want nil", con)
+	}
+
+	if !pat.HasConstraints() {
+		t.Errorf("HasConstraints: got false")
+	}
+
+	for _, test := range []struct {
+		in       string
+		contains string
+	}{
+		{"/{id:}", "invalid constraint"},
+		{"/{id:[a-}", "invalid constraint"},
+		{"/{rest:int...}", "not supported"},
+	} {
+		_, err := httppattern.ParsePattern(test.in)
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("%q: got %v, want error containing %q", test.in, err, test.contains)
+		}
+	}
+}
+
+func TestBuildConstraints(t *testing.T) {
+	t.Parallel()
+
+	pat, err := httppattern.ParsePattern("/users/{id:int}")
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	out, err := httppattern.Build(pat, "123")
+	if err != nil || out != "/users/123" {
+		t.Errorf("got %q, %v", out, err)
+	}
+
+	_, err = httppattern.Build(pat, "abc")
+	if err == nil || !strings.Contains(err.Error(), `does not satisfy constraint "int"`) {
+		t.Errorf("got %v, want constraint error", err)
+	}
+}
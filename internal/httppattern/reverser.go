@@ -1,23 +1,163 @@
 package httppattern
 
 import (
+	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/cockroachdb/errors"
 )
 
-// Pattern exposes the private type /net/http.pattern.
-type Pattern pattern
+// Pattern exposes the private type /net/http.pattern, extended with wildcard constraints.
+type Pattern struct {
+	*pattern
 
-// ParsePattern parses 's' as a patterned route for Go 1.22's ServeMux.
+	str         string                 // original string, including constraints.
+	constraints map[string]*Constraint // constraints by wildcard name.
+}
+
+// ParsePattern parses 's' as a patterned route for Go 1.22's ServeMux. On top of the standard syntax a
+// single-segment wildcard can be constrained as "{name:expr}", see [NewConstraint]. Constraints cannot
+// contain a '/'.
 func ParsePattern(s string) (*Pattern, error) {
-	p, err := parsePattern(s)
+	stripped, constraints, err := stripConstraints(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parsePattern(stripped)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pattern{pattern: p, str: s, constraints: constraints}, nil
+}
+
+// CleanPath returns the canonical path for p, the same way the standard library's ServeMux cleans request
+// paths before matching them.
+func CleanPath(p string) string { return cleanPath(p) }
+
+// Segment is the exported view of a single parsed path segment.
+type Segment struct {
+	Value      string      // literal text, wildcard name or "/" for a trailing "{$}".
+	Wild       bool        // whether the segment is a wildcard.
+	Multi      bool        // whether the segment is a "..." wildcard.
+	Constraint *Constraint // constraint of the wildcard, nil if unconstrained.
+}
+
+// String returns the original pattern string.
+func (p *Pattern) String() string { return p.str }
+
+// StdString returns the pattern string without constraints, as understood by the standard library.
+func (p *Pattern) StdString() string { return p.pattern.str }
+
+// Constraint returns the constraint of the wildcard 'name', or nil if it is unconstrained.
+func (p *Pattern) Constraint(name string) *Constraint { return p.constraints[name] }
+
+// HasConstraints reports whether any wildcard of the pattern is constrained.
+func (p *Pattern) HasConstraints() bool { return len(p.constraints) > 0 }
+
+// Method returns the method of the pattern, or the empty string if it matches any method.
+func (p *Pattern) Method() string { return p.method }
+
+// Host returns the host of the pattern, or the empty string if it matches any host.
+func (p *Pattern) Host() string { return p.host }
+
+// Path returns the path part of the original pattern string, without method and host.
+func (p *Pattern) Path() string {
+	rest := p.str
+	if p.method != "" {
+		rest = strings.TrimLeft(rest[len(p.method):], " \t")
+	}
+
+	return rest[len(p.host):]
+}
+
+// Segments returns a copy of the parsed path segments.
+func (p *Pattern) Segments() []Segment {
+	segs := make([]Segment, 0, len(p.segments))
+	for _, seg := range p.segments {
+		exp := Segment{Value: seg.s, Wild: seg.wild, Multi: seg.multi}
+		if seg.wild {
+			exp.Constraint = p.Constraint(seg.s)
+		}
+
+		segs = append(segs, exp)
+	}
+
+	return segs
+}
+
+// Wildcards returns the names of the wildcards in the pattern, in order. The anonymous wildcard
+// of a path that ends in '/' is not included.
+func (p *Pattern) Wildcards() []string {
+	var names []string
+
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			names = append(names, seg.s)
+		}
+	}
+
+	return names
+}
+
+// Values returns the values of the wildcards of the pattern, in the order of [Pattern.Wildcards], for an
+// escaped path that the pattern is known to match. Values are unescaped the same way as the standard
+// library's ServeMux does for [net/http.Request.PathValue].
+func (p *Pattern) Values(escapedPath string) []string {
+	var vals []string
+
+	rest := escapedPath
+	for _, seg := range p.segments {
+		switch {
+		case seg.multi:
+			if seg.s != "" {
+				vals = append(vals, pathUnescape(strings.TrimPrefix(rest, "/")))
+			}
+
+			return vals
+		case seg.s == "/" && !seg.wild:
+			return vals
+		}
+
+		rest = strings.TrimPrefix(rest, "/")
+
+		var value string
+		value, rest, _ = strings.Cut(rest, "/")
+		rest = "/" + rest
+
+		if seg.wild {
+			vals = append(vals, pathUnescape(value))
+		}
+	}
 
-	return (*Pattern)(p), err
+	return vals
 }
 
-// Build constructs a full url given the pattern 'pat' and 'vals' for wildcards.
+// Builder constructs urls from patterns. The zero value escapes wildcard values so that they round-trip
+// through [net/http.Request.PathValue].
+type Builder struct {
+	// RejectSlash causes building to fail when a value for a single-segment wildcard contains a '/',
+	// instead of escaping it as "%2F".
+	RejectSlash bool
+}
+
+// Build constructs a full url given the pattern 'pat' and 'vals' for wildcards, using the zero [Builder].
 func Build(pat *Pattern, vals ...string) (string, error) {
+	return Builder{}.Build(pat, vals...)
+}
+
+// BuildNamed constructs a full url given the pattern 'pat' and 'vals' for wildcards by name, using the
+// zero [Builder].
+func BuildNamed(pat *Pattern, vals map[string]string) (string, error) {
+	return Builder{}.BuildNamed(pat, vals)
+}
+
+// Build constructs a full url given the pattern 'pat' and 'vals' for wildcards. Values of single-segment
+// wildcards are escaped as a whole, values of "..." wildcards are escaped per segment while keeping the
+// '/' separators.
+func (b Builder) Build(pat *Pattern, vals ...string) (string, error) {
 	var res strings.Builder
 
 	// always write the host (if any)
@@ -43,7 +183,12 @@ func Build(pat *Pattern, vals ...string) (string, error) {
 				if vidx <= (len(vals) - 1) {
 					// if there is another value we add it since a trailingn '/' acts as a
 					// wildcard.
-					res.WriteString(vals[vidx])
+					escaped, err := escapeMulti(vals[vidx])
+					if err != nil {
+						return "", errors.Wrapf(err, "invalid value for trailing wildcard of pattern %q", pat.str)
+					}
+
+					res.WriteString(escaped)
 
 					vused++
 				}
@@ -56,7 +201,17 @@ func Build(pat *Pattern, vals ...string) (string, error) {
 				return "", errors.Newf("not enough values for pattern %q, expect at least: %d", pat.str, vidx+1)
 			}
 
-			res.WriteString(vals[vidx])
+			if c := pat.Constraint(seg.s); c != nil && !c.Match(vals[vidx]) {
+				return "", errors.Newf("value %q for wildcard %q of pattern %q does not satisfy constraint %q",
+					vals[vidx], seg.s, pat.str, c)
+			}
+
+			escaped, err := b.escapeValue(seg, vals[vidx])
+			if err != nil {
+				return "", errors.Wrapf(err, "invalid value for wildcard %q of pattern %q", seg.s, pat.str)
+			}
+
+			res.WriteString(escaped)
 
 			vidx++
 			vused++
@@ -73,3 +228,82 @@ func Build(pat *Pattern, vals ...string) (string, error) {
 
 	return res.String(), nil
 }
+
+// escapeValue escapes 'val' for the wildcard segment 'seg'.
+func (b Builder) escapeValue(seg segment, val string) (string, error) {
+	if seg.multi {
+		return escapeMulti(val)
+	}
+
+	if b.RejectSlash && strings.Contains(val, "/") {
+		return "", errors.Newf("value %q contains a '/'", val)
+	}
+
+	switch val {
+	case "":
+		return "", errors.New("value is empty")
+	case "/":
+		// the standard library mux treats a segment that unescapes to "/" as a trailing slash, which a
+		// single-segment wildcard never matches.
+		return "", errors.New(`value "/" cannot be matched by a single-segment wildcard`)
+	}
+
+	return escapeSegment(val), nil
+}
+
+// escapeMulti escapes every '/' separated segment of 'val'. Empty segments would be removed when the
+// request path is cleaned, so they are only allowed at the end (a trailing slash).
+func escapeMulti(val string) (string, error) {
+	segs := strings.Split(val, "/")
+	for i, seg := range segs {
+		if seg == "" && i < len(segs)-1 {
+			return "", errors.Newf("value %q contains an empty path segment", val)
+		}
+
+		segs[i] = escapeSegment(seg)
+	}
+
+	return strings.Join(segs, "/"), nil
+}
+
+// escapeSegment escapes a single path segment. Dot segments are escaped as well since they would
+// otherwise be removed when the request path is cleaned.
+func escapeSegment(seg string) string {
+	if seg == "." || seg == ".." {
+		return strings.Repeat("%2E", len(seg))
+	}
+
+	return url.PathEscape(seg)
+}
+
+// BuildNamed constructs a full url given the pattern 'pat' and 'vals' for wildcards by name. Every
+// wildcard of the pattern must be given a value, and every value must belong to a wildcard.
+func (b Builder) BuildNamed(pat *Pattern, vals map[string]string) (string, error) {
+	names := pat.Wildcards()
+
+	var unknown []string
+
+	for name := range vals {
+		if !slices.Contains(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return "", errors.Newf("pattern %q has no wildcard(s) named: %q, expect: %q", pat.str, unknown, names)
+	}
+
+	ordered := make([]string, 0, len(names))
+
+	for _, name := range names {
+		val, ok := vals[name]
+		if !ok {
+			return "", errors.Newf("missing value for wildcard %q of pattern %q", name, pat.str)
+		}
+
+		ordered = append(ordered, val)
+	}
+
+	return b.Build(pat, ordered...)
+}
@@ -1,8 +1,14 @@
 package httppattern_test
 
 import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/quick"
 
 	"github.com/advdv/bhttp/internal/httppattern"
 )
@@ -33,6 +39,11 @@ func TestReversing(t *testing.T) {
 		{"/foo///./../bar", []string{}, "/foo///./../bar"},
 		{"a.com/foo//", []string{}, "a.com/foo//"},
 		{"/%61%62/%7b/%", []string{}, "/ab/{/%"},
+		{"/{id}", []string{"a/b?c#d%e"}, "/a%2Fb%3Fc%23d%25e"},
+		{"/{id}", []string{".."}, "/%2E%2E"},
+		{"/{id}", []string{"héllo wörld"}, "/h%C3%A9llo%20w%C3%B6rld"},
+		{"/files/{rest...}", []string{"a b/c?d/"}, "/files/a%20b/c%3Fd/"},
+		{"/files/", []string{"./x#y"}, "/files/%2E/x%23y"},
 	} {
 		pat, err := httppattern.ParsePattern(test.in)
 		if err != nil {
@@ -61,6 +72,10 @@ func TestReversingError(t *testing.T) {
 		{"/{w1}/lit/{w2}", []string{"111"}, `not enough values for pattern "/{w1}/lit/{w2}", expect at least: 2`},
 		{"/", []string{"dd/bb", "err"}, `too many values for pattern "/", got: 2, used: 1`},
 		{"/a", []string{"dd/bb"}, `too many values for pattern "/a", got: 1, used: 0`},
+		{"/{id}", []string{""}, `invalid value for wildcard "id" of pattern "/{id}": value is empty`},
+		{"/{id}", []string{"/"}, `value "/" cannot be matched by a single-segment wildcard`},
+		{"/{rest...}", []string{"a//b"}, `value "a//b" contains an empty path segment`},
+		{"/", []string{"/a"}, `value "/a" contains an empty path segment`},
 	} {
 		pat, err := httppattern.ParsePattern(test.in)
 		if err != nil {
@@ -73,3 +88,205 @@ func TestReversingError(t *testing.T) {
 		}
 	}
 }
+
+func TestReversingNamed(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		in       string
+		vals     map[string]string
+		out      string
+		contains string
+	}{
+		{"/", nil, "/", ""},
+		{"/{w1}/lit/{w2}", map[string]string{"w2": "foo", "w1": "111"}, "/111/lit/foo", ""},
+		{"/{a}/foo/{rest...}", map[string]string{"rest": "d/a/f", "a": "786"}, "/786/foo/d/a/f", ""},
+		{"/{w1}/lit/{w2}", map[string]string{"w1": "111"}, "", `missing value for wildcard "w2"`},
+		{"/{w1}", map[string]string{"w1": "1", "zz": "2", "aa": "3"}, "", `has no wildcard(s) named: ["aa" "zz"]`},
+	} {
+		pat, err := httppattern.ParsePattern(test.in)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		out, err := httppattern.BuildNamed(pat, test.vals)
+		if test.contains != "" {
+			if err == nil || !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("%q:\ngot %v, want error containing %q", test.in, err, test.contains)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if out != test.out {
+			t.Errorf("got: %q, want: %q", out, test.out)
+		}
+	}
+}
+
+func TestReversingRejectSlash(t *testing.T) {
+	t.Parallel()
+
+	pat, err := httppattern.ParsePattern("/{id}/{rest...}")
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	strict := httppattern.Builder{RejectSlash: true}
+
+	if _, err := strict.Build(pat, "a/b", "c"); err == nil || !strings.Contains(err.Error(), `value "a/b" contains a '/'`) {
+		t.Errorf("got: %v, want error for slash", err)
+	}
+
+	out, err := strict.Build(pat, "a", "b/c")
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if out != "/a/b/c" {
+		t.Errorf("got: %q, want: %q", out, "/a/b/c")
+	}
+}
+
+// roundTrip builds a url for 'pattern' from 'vals', serves it through the standard library mux and
+// returns the values that the handler observes through PathValue.
+func roundTrip(t *testing.T, pattern string, vals ...string) ([]string, bool) {
+	t.Helper()
+
+	pat, err := httppattern.ParsePattern(pattern)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	loc, err := httppattern.Build(pat, vals...)
+	if err != nil {
+		t.Fatalf("build %q with %q: %v", pattern, vals, err)
+	}
+
+	u, err := url.Parse(loc)
+	if err != nil {
+		t.Fatalf("parse %q: %v", loc, err)
+	}
+
+	var got []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(_ http.ResponseWriter, r *http.Request) {
+		for _, name := range pat.Wildcards() {
+			got = append(got, r.PathValue(name))
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, &http.Request{Method: http.MethodGet, URL: u, Header: http.Header{}})
+
+	return got, rec.Code == http.StatusOK
+}
+
+// randomValue generates a string that is biased towards characters that are special in urls.
+func randomValue(rnd *rand.Rand, special string) string {
+	chars := []rune(special)
+
+	var b strings.Builder
+	for range rnd.Intn(8) {
+		if rnd.Intn(2) == 0 {
+			b.WriteRune(chars[rnd.Intn(len(chars))])
+		} else {
+			b.WriteRune(rune(rnd.Intn(0x2FF)))
+		}
+	}
+
+	return b.String()
+}
+
+func TestReversingRoundTripProperty(t *testing.T) {
+	t.Parallel()
+
+	single := func(val string) bool {
+		if val == "" || val == "/" {
+			return true // not representable, rejected by Build
+		}
+
+		got, ok := roundTrip(t, "/items/{id}/edit", val)
+
+		return ok && len(got) == 1 && got[0] == val
+	}
+
+	multi := func(segs []string) bool {
+		for _, seg := range segs {
+			if seg == "" || strings.Contains(seg, "/") {
+				return true // empty segments are removed by path cleaning, rejected by Build
+			}
+		}
+
+		val := strings.Join(segs, "/")
+		got, ok := roundTrip(t, "/files/{id}/{rest...}", "x", val)
+
+		return ok && len(got) == 2 && got[0] == "x" && got[1] == val
+	}
+
+	singleCfg := &quick.Config{MaxCount: 500, Values: func(vals []reflect.Value, rnd *rand.Rand) {
+		vals[0] = reflect.ValueOf(randomValue(rnd, "/?#%.;:@&=+$, \x00éü{}"))
+	}}
+
+	multiCfg := &quick.Config{MaxCount: 500, Values: func(vals []reflect.Value, rnd *rand.Rand) {
+		segs := make([]string, rnd.Intn(4))
+		for i := range segs {
+			segs[i] = randomValue(rnd, "?#%.;:@&=+$, \x00éü{}")
+		}
+
+		vals[0] = reflect.ValueOf(segs)
+	}}
+
+	if err := quick.Check(single, singleCfg); err != nil {
+		t.Errorf("single segment values do not round-trip: %v", err)
+	}
+
+	if err := quick.Check(multi, multiCfg); err != nil {
+		t.Errorf("multi segment values do not round-trip: %v", err)
+	}
+
+	for _, val := range []string{".", "..", "%", "%2F", "a/b", "//", "?", "#", " "} {
+		if !single(val) {
+			t.Errorf("value %q does not round-trip", val)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		pattern string
+		path    string
+	}{
+		{"/items/{id}", "/items/42"},
+		{"/items/{id}/", "/items/a%2Fb/rest/of/it"},
+		{"/items/{id}/{$}", "/items/x%20y/"},
+		{"GET example.com/{org}/items/{rest...}", "/acme/items/a/b%2Fc"},
+		{"/{a}/{b}", "/%E2%9C%93/100%25"},
+	} {
+		pat, err := httppattern.ParsePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", test.pattern, err)
+		}
+
+		var want []string
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(pat.StdString(), func(_ http.ResponseWriter, r *http.Request) {
+			for _, name := range pat.Wildcards() {
+				want = append(want, r.PathValue(name))
+			}
+		})
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com"+test.path, nil))
+
+		if got := pat.Values(test.path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s %s: got %q, want %q", test.pattern, test.path, got, want)
+		}
+	}
+}
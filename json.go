@@ -0,0 +1,196 @@
+package bhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/advdv/bhttp/openapi"
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultJSONBodyLimit is the maximum size of a request body decoded by [JSON], unless configured
+// otherwise with [WithJSONBodyLimit].
+const DefaultJSONBodyLimit = 1 << 20
+
+// JSONOption configures a handler created with [JSON].
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	status        int
+	bodyLimit     int64
+	unknownFields bool
+	errors        []Code
+}
+
+// WithJSONStatus sets the status code of successful responses, for example [http.StatusCreated] for handlers
+// that create a resource. It defaults to [http.StatusOK]. No body is written for [http.StatusNoContent].
+func WithJSONStatus(code int) JSONOption {
+	return func(o *jsonOptions) { o.status = code }
+}
+
+// WithJSONBodyLimit sets the maximum size of the request body in bytes. Larger bodies are rejected with
+// [CodeRequestEntityTooLarge]. It defaults to [DefaultJSONBodyLimit].
+func WithJSONBodyLimit(n int64) JSONOption {
+	return func(o *jsonOptions) { o.bodyLimit = n }
+}
+
+// AllowUnknownFields accepts request bodies with fields that are not present in the input type. By default
+// such bodies are rejected with [CodeBadRequest].
+func AllowUnknownFields() JSONOption {
+	return func(o *jsonOptions) { o.unknownFields = true }
+}
+
+// WithJSONErrors declares the error codes the function can return, so they are part of the OpenAPI document
+// generated by [ServeMux.OpenAPI]. It does not change how errors are rendered.
+func WithJSONErrors(codes ...Code) JSONOption {
+	return func(o *jsonOptions) { o.errors = append(o.errors, codes...) }
+}
+
+// jsonHandler implements [Handler] for [JSON].
+type jsonHandler[In, Out any] struct {
+	fn   func(context.Context, In) (Out, error)
+	opts jsonOptions
+}
+
+// JSON returns a handler that decodes the request body as JSON into In, calls 'fn' and encodes its
+// result as the JSON response.
+//
+// Bodies that are not of a JSON content type are rejected with [CodeUnsupportedMediaType], bodies that
+// are not valid JSON, hold more than one value or have unknown fields with [CodeBadRequest]. When In
+// implements a "Validate() error" method it is called after decoding, and an error that is not already an
+// [*Error] is returned as [CodeUnprocessableEntity]. Errors returned by 'fn' are rendered like those of
+// any other handler. An input type of struct{} accepts an empty body, which suits handlers that take no
+// input.
+func JSON[In, Out any](fn func(ctx context.Context, in In) (Out, error), opts ...JSONOption) Handler {
+	h := &jsonHandler[In, Out]{fn: fn, opts: jsonOptions{status: http.StatusOK, bodyLimit: DefaultJSONBodyLimit}}
+	for _, opt := range opts {
+		opt(&h.opts)
+	}
+
+	return h
+}
+
+// ServeBHTTP implements the [Handler] interface.
+func (h *jsonHandler[In, Out]) ServeBHTTP(ctx context.Context, w ResponseWriter, r *http.Request) error {
+	in, err := h.decode(w, r)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := any(&in).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			if _, ok := asError(err); ok {
+				return err
+			}
+
+			return NewError(CodeUnprocessableEntity, err)
+		}
+	}
+
+	out, err := h.fn(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	if h.opts.status == http.StatusNoContent {
+		w.WriteHeader(h.opts.status)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.opts.status)
+
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		return errors.Wrap(err, "failed to encode response")
+	}
+
+	return nil
+}
+
+// describeOperation implements the operationDescriber interface.
+func (h *jsonHandler[In, Out]) describeOperation(op *openapi.Operation, gen *openapi.Generator) {
+	if in := reflect.TypeFor[In](); !isEmptyStruct(in) {
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]*openapi.MediaType{"application/json": {Schema: gen.Schema(in)}},
+		}
+
+		errorResponses(op, CodeBadRequest, CodeRequestEntityTooLarge, CodeUnsupportedMediaType)
+	}
+
+	if _, ok := any(new(In)).(interface{ Validate() error }); ok {
+		errorResponses(op, CodeUnprocessableEntity)
+	}
+
+	errorResponses(op, h.opts.errors...)
+
+	res := &openapi.Response{Description: http.StatusText(h.opts.status)}
+	if h.opts.status != http.StatusNoContent {
+		res.Content = map[string]*openapi.MediaType{"application/json": {Schema: gen.Schema(reflect.TypeFor[Out]())}}
+	}
+
+	op.Responses[strconv.Itoa(h.opts.status)] = res
+}
+
+func (h *jsonHandler[In, Out]) decode(w ResponseWriter, r *http.Request) (in In, err error) {
+	if r.ContentLength == 0 && isEmptyStruct(reflect.TypeFor[In]()) {
+		return in, nil
+	}
+
+	if ct := r.Header.Get("Content-Type"); !isJSONContentType(ct) {
+		return in, NewError(CodeUnsupportedMediaType,
+			errors.Newf("content type %q is not supported, expect: application/json", ct))
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.opts.bodyLimit))
+	if !h.opts.unknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(&in); err != nil {
+		return in, decodeError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err == nil {
+			err = errors.New("body must contain a single JSON value")
+		}
+
+		return in, decodeError(err)
+	}
+
+	return in, nil
+}
+
+// decodeError turns an error from decoding the request body into the error that is returned.
+func decodeError(err error) error {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return bodyTooLargeError(maxErr.Limit)
+	case errors.Is(err, io.EOF):
+		return NewError(CodeBadRequest, errors.New("invalid JSON body: body is empty"))
+	default:
+		return NewError(CodeBadRequest, errors.Wrap(err, "invalid JSON body"))
+	}
+}
+
+// isJSONContentType reports whether 'ct' is "application/json" or a structured "+json" media type.
+func isJSONContentType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/json" || (strings.HasPrefix(mt, "application/") && strings.HasSuffix(mt, "+json"))
+}
+
+func isEmptyStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 0
+}
@@ -0,0 +1,115 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type createUserInput struct {
+	Name string `json:"name"`
+}
+
+func (in createUserInput) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+
+	return nil
+}
+
+type createUserOutput struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func serveJSON(t *testing.T, h bhttp.Handler, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := bhttp.NewServeMux()
+	mux.Handle("POST /users", h)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestJSON(t *testing.T) {
+	createUser := bhttp.JSON(func(_ context.Context, in createUserInput) (createUserOutput, error) {
+		if in.Name == "taken" {
+			return createUserOutput{}, bhttp.NewError(bhttp.CodeConflict, errors.New("name is taken"))
+		}
+
+		return createUserOutput{ID: 1, Name: in.Name}, nil
+	}, bhttp.WithJSONStatus(http.StatusCreated), bhttp.WithJSONBodyLimit(64))
+
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{"created", "application/json", `{"name":"alice"}`, http.StatusCreated, `{"id":1,"name":"alice"}` + "\n"},
+		{"charset", "application/json; charset=utf-8", `{"name":"bob"}`, http.StatusCreated, `{"id":1,"name":"bob"}` + "\n"},
+		{"structured suffix", "application/merge-patch+json", `{"name":"c"}`, http.StatusCreated, `{"id":1,"name":"c"}` + "\n"},
+		{"no content type", "", `{"name":"alice"}`, http.StatusUnsupportedMediaType,
+			"Unsupported Media Type: content type \"\" is not supported, expect: application/json\n"},
+		{"text", "text/plain", `{"name":"alice"}`, http.StatusUnsupportedMediaType,
+			"Unsupported Media Type: content type \"text/plain\" is not supported, expect: application/json\n"},
+		{"empty", "application/json", ``, http.StatusBadRequest, "Bad Request: invalid JSON body: body is empty\n"},
+		{"malformed", "application/json", `{"name":`, http.StatusBadRequest, "Bad Request: invalid JSON body: unexpected EOF\n"},
+		{"unknown field", "application/json", `{"nam":"x"}`, http.StatusBadRequest,
+			"Bad Request: invalid JSON body: json: unknown field \"nam\"\n"},
+		{"trailing", "application/json", `{"name":"a"} {}`, http.StatusBadRequest,
+			"Bad Request: invalid JSON body: body must contain a single JSON value\n"},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge,
+			"Request Entity Too Large: request body exceeds 64 bytes\n"},
+		{"invalid", "application/json", `{"name":""}`, http.StatusUnprocessableEntity,
+			"Unprocessable Entity: name is required\n"},
+		{"handler error", "application/json", `{"name":"taken"}`, http.StatusConflict, "Conflict: name is taken\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serveJSON(t, createUser, test.contentType, test.body)
+			require.Equal(t, test.wantCode, rec.Code)
+			require.Equal(t, test.wantBody, rec.Body.String())
+
+			if test.wantCode == http.StatusCreated {
+				require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestJSONOptions(t *testing.T) {
+	t.Run("allow unknown fields", func(t *testing.T) {
+		h := bhttp.JSON(func(_ context.Context, in createUserInput) (createUserOutput, error) {
+			return createUserOutput{Name: in.Name}, nil
+		}, bhttp.AllowUnknownFields())
+
+		rec := serveJSON(t, h, "application/json", `{"name":"a","extra":true}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, `{"id":0,"name":"a"}`+"\n", rec.Body.String())
+	})
+
+	t.Run("no input and no content", func(t *testing.T) {
+		h := bhttp.JSON(func(context.Context, struct{}) (struct{}, error) {
+			return struct{}{}, nil
+		}, bhttp.WithJSONStatus(http.StatusNoContent))
+
+		rec := serveJSON(t, h, "", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Empty(t, rec.Body.String())
+	})
+}
@@ -0,0 +1,307 @@
+package bhttp
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultJWKSRefresh is how long [JWKS] caches the key set before fetching it again.
+const DefaultJWKSRefresh = time.Hour
+
+// DefaultJWKSMinRefresh is the minimum time between two fetches of the key set, so that tokens with an
+// unknown key id, or an unavailable identity provider, do not cause a fetch for every request.
+const DefaultJWKSMinRefresh = time.Minute
+
+// DefaultJWKSFetchTimeout bounds how long a fetch of the key set may take.
+const DefaultJWKSFetchTimeout = 10 * time.Second
+
+// maxJWKSSize limits the size of a fetched key set.
+const maxJWKSSize = 1 << 20
+
+// JWKS is a [KeySet] that fetches a JSON Web Key Set from a URL and caches it. The set is fetched on first
+// use, again once the refresh interval has passed, and early when a token refers to an unknown key id so
+// that rotated keys are picked up. When a fetch fails, previously fetched keys remain in use.
+type JWKS struct {
+	url        string
+	client     *http.Client
+	refresh    time.Duration
+	minRefresh time.Duration
+	timeout    time.Duration
+
+	mu         sync.Mutex
+	keys       []jwk
+	fetched    time.Time
+	attempted  time.Time
+	fetchErr   error         // error of the last fetch.
+	refreshing chan struct{} // closed when the fetch in progress completes, nil when none is.
+}
+
+// JWKSOption configures a [JWKS].
+type JWKSOption func(*JWKS)
+
+// WithJWKSClient sets the client that fetches the key set, for example one with a traced transport. It
+// defaults to [http.DefaultClient].
+func WithJWKSClient(c *http.Client) JWKSOption {
+	return func(s *JWKS) { s.client = c }
+}
+
+// WithJWKSRefresh sets how long the key set is cached, it defaults to [DefaultJWKSRefresh].
+func WithJWKSRefresh(d time.Duration) JWKSOption {
+	return func(s *JWKS) { s.refresh = d }
+}
+
+// WithJWKSMinRefresh sets the minimum time between fetches, it defaults to [DefaultJWKSMinRefresh].
+func WithJWKSMinRefresh(d time.Duration) JWKSOption {
+	return func(s *JWKS) { s.minRefresh = d }
+}
+
+// WithJWKSFetchTimeout sets how long a fetch of the key set may take, it defaults to
+// [DefaultJWKSFetchTimeout].
+func WithJWKSFetchTimeout(d time.Duration) JWKSOption {
+	return func(s *JWKS) { s.timeout = d }
+}
+
+// NewJWKS inits a key set that is fetched from 'url', such as the "jwks_uri" of an OpenID provider.
+func NewJWKS(url string, opts ...JWKSOption) *JWKS {
+	s := &JWKS{
+		url:        url,
+		client:     http.DefaultClient,
+		refresh:    DefaultJWKSRefresh,
+		minRefresh: DefaultJWKSMinRefresh,
+		timeout:    DefaultJWKSFetchTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Key implements [KeySet]. It returns an error wrapping [ErrUnknownKey] when no key in the set matches.
+// The key set is fetched without holding the lock, and only by one request at a time: while a fetch is in
+// progress, other requests use the cached keys, or wait for the fetch when their key is not cached. Since
+// others may wait for it, the fetch is not canceled with the context of the request that started it, but
+// is bounded by the fetch timeout instead.
+func (s *JWKS) Key(ctx context.Context, kid, alg string) (any, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	key, found := lookupJWK(s.keys, kid, alg)
+	stale := now.Sub(s.fetched) >= s.refresh
+	refresh := s.fetched.IsZero() || ((stale || !found) && now.Sub(s.attempted) >= s.minRefresh)
+
+	done, owner := s.refreshing, false
+	if refresh && done == nil {
+		s.attempted, owner = now, true
+		s.refreshing = make(chan struct{})
+		done = s.refreshing
+	}
+	s.mu.Unlock()
+
+	switch {
+	case owner:
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+		keys, err := s.fetch(fetchCtx)
+		cancel()
+
+		s.mu.Lock()
+		if s.fetchErr = err; err == nil {
+			s.keys, s.fetched = keys, time.Now()
+		}
+
+		s.refreshing = nil
+		close(done)
+		s.mu.Unlock()
+	case done != nil && !found:
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "failed to wait for key set")
+		}
+	case found:
+		return key, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fetched.IsZero() && s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+
+	if key, found = lookupJWK(s.keys, kid, alg); !found {
+		return nil, errors.Wrapf(ErrUnknownKey, "no key with id %q for algorithm %q", kid, alg)
+	}
+
+	return key, nil
+}
+
+// fetch downloads and parses the key set. Keys of unsupported types are skipped.
+func (s *JWKS) fetch(ctx context.Context) ([]jwk, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create key set request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch key set")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Newf("failed to fetch key set: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "failed to decode key set")
+	}
+
+	keys := set.Keys[:0]
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		if k.key, err = k.parse(); err == nil {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys, nil
+}
+
+// lookupJWK finds the key for a token. Without a key id the first key that fits the algorithm is used.
+func lookupJWK(keys []jwk, kid, alg string) (any, bool) {
+	for _, k := range keys {
+		if (kid == "" || k.Kid == kid) && k.fits(alg) {
+			return k.key, true
+		}
+	}
+
+	return nil, false
+}
+
+// jwk is a single key of a JSON Web Key Set, RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+
+	key any
+}
+
+// fits reports whether the key may verify tokens signed with the algorithm.
+func (k jwk) fits(alg string) bool {
+	if k.Alg != "" && k.Alg != alg {
+		return false
+	}
+
+	switch alg {
+	case AlgRS256:
+		return k.Kty == "RSA"
+	case AlgES256:
+		return k.Kty == "EC"
+	case AlgEdDSA:
+		return k.Kty == "OKP"
+	case AlgHS256:
+		return k.Kty == "oct"
+	default:
+		return false
+	}
+}
+
+// parse decodes the key material into the type that [verifySignature] expects.
+func (k jwk) parse() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.Newf("unsupported curve %q", k.Crv)
+		}
+
+		x, errx := base64.RawURLEncoding.DecodeString(k.X)
+		y, erry := base64.RawURLEncoding.DecodeString(k.Y)
+		if errx != nil || erry != nil || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid EC coordinates")
+		}
+
+		// let crypto/ecdh check that the point is on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, errors.Wrap(err, "invalid EC point")
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) < 1 {
+			return nil, errors.New("invalid symmetric key")
+		}
+
+		return secret, nil
+	default:
+		return nil, errors.Newf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeJWKInt decodes a base64url encoded big-endian integer.
+func decodeJWKInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) < 1 {
+		return nil, errors.New("invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
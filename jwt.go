@@ -0,0 +1,424 @@
+package bhttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// The signature algorithms that [JWTAuth] supports.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// DefaultClockSkew is the leeway that [JWTAuth] allows when checking the "exp" and "nbf" claims.
+const DefaultClockSkew = time.Minute
+
+// ErrUnknownKey is returned by a [KeySet] that has no key for the token. [JWTAuth] answers such tokens
+// with [CodeUnauthorized], while any other key set error is treated as a server error.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet provides the key that verifies a token, given the "kid" and "alg" of its header. The key must
+// be a *rsa.PublicKey for RS256, a P-256 *ecdsa.PublicKey for ES256, an ed25519.PublicKey for EdDSA or a
+// []byte secret for HS256.
+type KeySet interface {
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// KeySetFunc allows a function to be used as a [KeySet].
+type KeySetFunc func(ctx context.Context, kid, alg string) (any, error)
+
+// Key implements [KeySet].
+func (f KeySetFunc) Key(ctx context.Context, kid, alg string) (any, error) { return f(ctx, kid, alg) }
+
+// HMACKey returns a key set that verifies HS256 tokens with a shared secret, regardless of their "kid".
+func HMACKey(secret []byte) KeySet {
+	return KeySetFunc(func(_ context.Context, _, alg string) (any, error) {
+		if alg != AlgHS256 {
+			return nil, errors.Wrapf(ErrUnknownKey, "no key for algorithm %q", alg)
+		}
+
+		return secret, nil
+	})
+}
+
+// Claims are the verified claims of a JWT. The registered claims are decoded into fields, all claims are
+// available through [Claims.Decode].
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	// Scopes holds the space separated "scope" claim, or the "scp" claim as issued by some providers.
+	Scopes []string
+	// Roles holds the "roles" claim.
+	Roles []string
+
+	raw json.RawMessage
+}
+
+// Decode unmarshals the full claim set into 'v', for reading private claims.
+func (c *Claims) Decode(v any) error {
+	return errors.Wrap(json.Unmarshal(c.raw, v), "failed to decode claims")
+}
+
+// HasScope reports whether the token was granted the scope. Together with [Claims.HasRole] it makes the
+// claims the [Principal] of the request.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasRole reports whether the token carries the role.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// JWTClaims returns the claims of the token that was verified by [JWTAuth], or nil if the request was
+// not authenticated.
+func JWTClaims(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(ctxKeyJWTClaims).(*Claims)
+	return claims
+}
+
+// JWTOption configures [JWTAuth].
+type JWTOption func(*jwtOptions)
+
+type jwtOptions struct {
+	issuers    []string
+	audiences  []string
+	algorithms []string
+	skew       time.Duration
+	realm      string
+	public     []string
+}
+
+// WithIssuer only accepts tokens with one of the given "iss" claims.
+func WithIssuer(issuers ...string) JWTOption {
+	return func(o *jwtOptions) { o.issuers = append(o.issuers, issuers...) }
+}
+
+// WithAudience only accepts tokens that are intended for one of the given audiences.
+func WithAudience(audiences ...string) JWTOption {
+	return func(o *jwtOptions) { o.audiences = append(o.audiences, audiences...) }
+}
+
+// WithAlgorithms restricts the accepted signature algorithms, all supported algorithms are accepted by
+// default. The algorithm must still match the type of the key.
+func WithAlgorithms(algs ...string) JWTOption {
+	return func(o *jwtOptions) { o.algorithms = algs }
+}
+
+// WithClockSkew sets the leeway for the time based claims, it defaults to [DefaultClockSkew].
+func WithClockSkew(d time.Duration) JWTOption {
+	return func(o *jwtOptions) { o.skew = d }
+}
+
+// WithRealm sets the realm of the "WWW-Authenticate" challenge.
+func WithRealm(realm string) JWTOption {
+	return func(o *jwtOptions) { o.realm = realm }
+}
+
+// WithPublicRoutes lists the names of routes that do not require a token, in addition to the routes that
+// are registered with the [Public] option. A valid token on such a route still makes its claims available,
+// an invalid one is rejected.
+func WithPublicRoutes(names ...string) JWTOption {
+	return func(o *jwtOptions) { o.public = append(o.public, names...) }
+}
+
+// JWTAuth returns middleware that verifies the bearer token of each request against the key set and
+// stores its claims in the context, see [JWTClaims], also as the [Principal] for route policies. Tokens
+// must carry an "exp" claim. Requests without a
+// valid token are answered with [CodeUnauthorized] and a "WWW-Authenticate" challenge as described by
+// RFC 6750. Requests that match no route are not authenticated, since no route declares whether they are
+// public, and reach the not found handler without claims.
+func JWTAuth(keys KeySet, opts ...JWTOption) Middleware {
+	o := jwtOptions{
+		algorithms: []string{AlgRS256, AlgES256, AlgHS256, AlgEdDSA},
+		skew:       DefaultClockSkew,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			route := MatchedRoute(r.Context())
+			if route == nil {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			token, ok := bearerToken(r.Header)
+			if !ok {
+				if route.Policy.Public || slices.Contains(o.public, route.Name) {
+					return next.ServeBareBHTTP(w, r)
+				}
+
+				return o.unauthorized(errors.New("missing bearer token"), false)
+			}
+
+			claims, err := o.verify(r.Context(), keys, token)
+			if err != nil {
+				if CodeOf(err) == CodeUnauthorized {
+					return err
+				}
+
+				return errors.Wrap(err, "failed to verify token")
+			}
+
+			ctx := WithPrincipal(context.WithValue(r.Context(), ctxKeyJWTClaims, claims), claims)
+
+			return next.ServeBareBHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(h http.Header) (string, bool) {
+	scheme, token, ok := strings.Cut(h.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+// unauthorized returns the 401 error with its challenge. The error attributes are only included when a
+// token was presented, as RFC 6750 prescribes.
+func (o *jwtOptions) unauthorized(err error, invalid bool) *Error {
+	var params []string
+	if o.realm != "" {
+		params = append(params, "realm="+strconv.Quote(o.realm))
+	}
+
+	if invalid {
+		params = append(params, `error="invalid_token"`, "error_description="+strconv.Quote(err.Error()))
+	}
+
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	e := NewError(CodeUnauthorized, err)
+	e.Header().Set("WWW-Authenticate", challenge)
+
+	return e
+}
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and claims of a compact serialized token.
+func (o *jwtOptions) verify(ctx context.Context, keys KeySet, token string) (*Claims, error) {
+	invalid := func(msg string) error { return o.unauthorized(errors.New(msg), true) }
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("token is malformed")
+	}
+
+	var hdr jwtHeader
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, invalid("token header is malformed")
+	}
+
+	if !slices.Contains(o.algorithms, hdr.Alg) {
+		return nil, invalid("token algorithm is not allowed")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("token signature is malformed")
+	}
+
+	key, err := keys.Key(ctx, hdr.Kid, hdr.Alg)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, invalid("token signing key is unknown")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get signing key")
+	}
+
+	if !verifySignature(hdr.Alg, key, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, invalid("token signature is invalid")
+	}
+
+	claims, err := parseClaims(parts[1])
+	if err != nil {
+		return nil, invalid("token claims are malformed")
+	}
+
+	now := time.Now()
+
+	switch {
+	case claims.ExpiresAt.IsZero():
+		return nil, invalid("token has no expiry")
+	case now.After(claims.ExpiresAt.Add(o.skew)):
+		return nil, invalid("token is expired")
+	case !claims.NotBefore.IsZero() && now.Before(claims.NotBefore.Add(-o.skew)):
+		return nil, invalid("token is not valid yet")
+	case len(o.issuers) > 0 && !slices.Contains(o.issuers, claims.Issuer):
+		return nil, invalid("token issuer is not accepted")
+	case len(o.audiences) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(o.audiences, aud)
+	}):
+		return nil, invalid("token audience is not accepted")
+	}
+
+	return claims, nil
+}
+
+// verifySignature checks the signature over the signing input. The key type must match the algorithm,
+// so that a public key can never be used as an HMAC secret.
+func verifySignature(alg string, key any, input, sig []byte) bool {
+	digest := sha256.Sum256(input)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgRS256 && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if alg != AlgES256 || key.Curve.Params().Name != "P-256" || len(sig) != 64 {
+			return false
+		}
+
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+
+		return ecdsa.Verify(key, digest[:], r, s)
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA && ed25519.Verify(key, input, sig)
+	case []byte:
+		if alg != AlgHS256 || len(key) < 1 {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write(input)
+
+		return hmac.Equal(mac.Sum(nil), sig)
+	default:
+		return false
+	}
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token.
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode segment")
+	}
+
+	return errors.Wrap(json.Unmarshal(data, v), "failed to unmarshal segment")
+}
+
+// parseClaims decodes the payload of a token. The audience may be a string or an array of strings and
+// the times are (possibly fractional) seconds since the epoch.
+func parseClaims(seg string) (*Claims, error) {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode claims")
+	}
+
+	var reg struct {
+		Iss   string          `json:"iss"`
+		Sub   string          `json:"sub"`
+		Aud   json.RawMessage `json:"aud"`
+		Exp   *float64        `json:"exp"`
+		Nbf   *float64        `json:"nbf"`
+		Iat   *float64        `json:"iat"`
+		Jti   string          `json:"jti"`
+		Scope string          `json:"scope"`
+		Scp   json.RawMessage `json:"scp"`
+		Roles json.RawMessage `json:"roles"`
+	}
+
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal claims")
+	}
+
+	claims := &Claims{
+		Issuer:    reg.Iss,
+		Subject:   reg.Sub,
+		ExpiresAt: numericDate(reg.Exp),
+		NotBefore: numericDate(reg.Nbf),
+		IssuedAt:  numericDate(reg.Iat),
+		ID:        reg.Jti,
+		Scopes:    strings.Fields(reg.Scope),
+		raw:       data,
+	}
+
+	if claims.Audience, err = stringOrArray(reg.Aud); err != nil {
+		return nil, errors.Wrap(err, "invalid audience")
+	}
+
+	if claims.Roles, err = stringOrArray(reg.Roles); err != nil {
+		return nil, errors.Wrap(err, "invalid roles")
+	}
+
+	if len(claims.Scopes) < 1 {
+		scp, err := stringOrArray(reg.Scp)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid scopes")
+		}
+
+		// providers such as Azure AD issue "scp" as a space separated string.
+		for _, scope := range scp {
+			claims.Scopes = append(claims.Scopes, strings.Fields(scope)...)
+		}
+	}
+
+	return claims, nil
+}
+
+// numericDate converts a JWT NumericDate, it returns the zero time if the claim is absent.
+func numericDate(secs *float64) time.Time {
+	if secs == nil {
+		return time.Time{}
+	}
+
+	whole := int64(*secs)
+
+	return time.Unix(whole, int64((*secs-float64(whole))*float64(time.Second)))
+}
+
+// stringOrArray decodes a claim that is either a single string or an array of strings.
+func stringOrArray(data json.RawMessage) ([]string, error) {
+	if len(data) < 1 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return nil, errors.Wrap(err, "must be a string or an array of strings")
+	}
+
+	return multi, nil
+}
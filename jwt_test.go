@@ -0,0 +1,459 @@
+package bhttp_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+var b64 = base64.RawURLEncoding
+
+// signJWT creates a compact token, signing it with the algorithm that fits the key type.
+func signJWT(t *testing.T, key any, kid string, claims map[string]any) string {
+	t.Helper()
+
+	var alg string
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		alg = bhttp.AlgRS256
+	case *ecdsa.PrivateKey:
+		alg = bhttp.AlgES256
+	case ed25519.PrivateKey:
+		alg = bhttp.AlgEdDSA
+	case []byte:
+		alg = bhttp.AlgHS256
+	}
+
+	return signJWTWith(t, alg, key, kid, claims)
+}
+
+func signJWTWith(t *testing.T, alg string, key any, kid string, claims map[string]any) string {
+	t.Helper()
+
+	hdr, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	input := b64.EncodeToString(hdr) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, []byte(input))
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	}
+
+	return input + "." + b64.EncodeToString(sig)
+}
+
+// publicJWK encodes the public part of a key as a JWK.
+func publicJWK(kid string, key any) map[string]string {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return map[string]string{
+			"kty": "RSA", "kid": kid, "use": "sig",
+			"n": b64.EncodeToString(key.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		return map[string]string{
+			"kty": "EC", "kid": kid, "crv": "P-256",
+			"x": b64.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y": b64.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}
+	case ed25519.PrivateKey:
+		return map[string]string{
+			"kty": "OKP", "kid": kid, "crv": "Ed25519",
+			"x": b64.EncodeToString(key.Public().(ed25519.PublicKey)),
+		}
+	case []byte:
+		return map[string]string{"kty": "oct", "kid": kid, "k": b64.EncodeToString(key)}
+	}
+
+	panic(fmt.Sprintf("unsupported key %T", key))
+}
+
+// jwksServer serves the keys as a JSON Web Key Set and counts the fetches.
+func jwksServer(t *testing.T, keys map[string]any) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var fetches atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+
+		set := []map[string]string{}
+		for kid, key := range keys {
+			set = append(set, publicJWK(kid, key))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"keys": set}))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &fetches
+}
+
+// tamper combines the header and claims of one token with the signature of another.
+func tamper(signed, forged string) string {
+	return forged[:strings.LastIndex(forged, ".")] + signed[strings.LastIndex(signed, "."):]
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+
+	srv, fetches := jwksServer(t, map[string]any{"rsa": rsaKey, "ec": ecKey, "ed": edKey, "oct": hmacKey})
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.JWTAuth(bhttp.NewJWKS(srv.URL, bhttp.WithJWKSClient(srv.Client())),
+		bhttp.WithIssuer("https://issuer.example"),
+		bhttp.WithAudience("api"),
+		bhttp.WithClockSkew(30*time.Second),
+		bhttp.WithRealm("example"),
+		bhttp.WithPublicRoutes("public"),
+	))
+
+	whoami := func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		claims := bhttp.JWTClaims(ctx)
+		if claims == nil {
+			_, err := fmt.Fprint(w, "anonymous")
+			return err
+		}
+
+		var private struct {
+			Tenant string `json:"tenant"`
+		}
+		if err := claims.Decode(&private); err != nil {
+			return err
+		}
+
+		_, err := fmt.Fprintf(w, "%s@%s %v", claims.Subject, private.Tenant, claims.HasScope("items:read"))
+
+		return err
+	}
+	mux.HandleFunc("GET /me", whoami, bhttp.Name("me"))
+	mux.HandleFunc("GET /public", whoami, bhttp.Name("public"))
+
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	now := time.Now()
+	claims := func(mod func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss": "https://issuer.example", "aud": []string{"other", "api"}, "sub": "alice",
+			"exp": now.Add(time.Minute).Unix(), "nbf": now.Unix(), "tenant": "acme", "scope": "items:read items:write",
+		}
+		if mod != nil {
+			mod(c)
+		}
+
+		return c
+	}
+
+	t.Run("verifies all algorithms", func(t *testing.T) {
+		for kid, key := range map[string]any{"rsa": rsaKey, "ec": ecKey, "ed": edKey, "oct": hmacKey} {
+			rec := serve("/me", signJWT(t, key, kid, claims(nil)))
+			require.Equal(t, http.StatusOK, rec.Code, kid)
+			require.Equal(t, "alice@acme true", rec.Body.String(), kid)
+		}
+
+		require.Equal(t, int64(1), fetches.Load(), "key set is cached")
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		rec := serve("/me", "")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, `Bearer realm="example"`, rec.Header().Get("WWW-Authenticate"))
+		require.Equal(t, "Unauthorized: missing bearer token\n", rec.Body.String())
+	})
+
+	for name, tc := range map[string]struct {
+		token string
+		desc  string
+	}{
+		"expired": {
+			signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() })),
+			"token is expired",
+		},
+		"no expiry": {
+			signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) { delete(c, "exp") })),
+			"token has no expiry",
+		},
+		"not yet valid": {
+			signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) { c["nbf"] = now.Add(time.Minute).Unix() })),
+			"token is not valid yet",
+		},
+		"wrong issuer": {
+			signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+			"token issuer is not accepted",
+		},
+		"wrong audience": {
+			signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) { c["aud"] = "other" })),
+			"token audience is not accepted",
+		},
+		"key of other type": {
+			signJWT(t, ecKey, "rsa", claims(nil)),
+			"token signing key is unknown",
+		},
+		"tampered": {
+			tamper(signJWT(t, rsaKey, "rsa", claims(nil)), signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) {
+				c["sub"] = "mallory"
+			}))),
+			"token signature is invalid",
+		},
+		"alg none": {
+			signJWTWith(t, "none", nil, "rsa", claims(nil)),
+			"token algorithm is not allowed",
+		},
+		"public key as hmac secret": {
+			signJWTWith(t, bhttp.AlgHS256, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), "rsa", claims(nil)),
+			"token signing key is unknown",
+		},
+		"malformed": {"abc", "token is malformed"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serve("/me", tc.token)
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			require.Equal(t,
+				`Bearer realm="example", error="invalid_token", error_description="`+tc.desc+`"`,
+				rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+
+	t.Run("scp claim", func(t *testing.T) {
+		for _, scp := range []any{"openid items:read", []string{"openid", "items:read"}} {
+			token := signJWT(t, rsaKey, "rsa", claims(func(c map[string]any) {
+				delete(c, "scope")
+				c["scp"] = scp
+			}))
+			require.Equal(t, "alice@acme true", serve("/me", token).Body.String())
+		}
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		token := signJWT(t, edKey, "ed", claims(func(c map[string]any) { c["exp"] = now.Add(-20 * time.Second).Unix() }))
+		require.Equal(t, http.StatusOK, serve("/me", token).Code)
+	})
+
+	t.Run("public routes", func(t *testing.T) {
+		require.Equal(t, "anonymous", serve("/public", "").Body.String())
+		require.Equal(t, "alice@acme true", serve("/public", signJWT(t, hmacKey, "oct", claims(nil))).Body.String())
+		require.Equal(t, http.StatusUnauthorized, serve("/public", "garbage").Code)
+	})
+
+	t.Run("unmatched requests", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve("/other", "").Code)
+	})
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := map[string]any{"old": oldKey}
+	srv, fetches := jwksServer(t, keys)
+	ctx := context.Background()
+
+	set := bhttp.NewJWKS(srv.URL, bhttp.WithJWKSMinRefresh(0))
+
+	_, err = set.Key(ctx, "old", bhttp.AlgES256)
+	require.NoError(t, err)
+
+	_, err = set.Key(ctx, "old", bhttp.AlgRS256)
+	require.ErrorIs(t, err, bhttp.ErrUnknownKey)
+
+	keys["new"] = newKey
+
+	key, err := set.Key(ctx, "new", bhttp.AlgES256)
+	require.NoError(t, err)
+	require.True(t, newKey.PublicKey.Equal(key))
+	require.Equal(t, int64(3), fetches.Load())
+
+	t.Run("keeps keys when the set is unavailable", func(t *testing.T) {
+		srv.Close()
+
+		_, err := set.Key(ctx, "new", bhttp.AlgES256)
+		require.NoError(t, err)
+
+		_, err = set.Key(ctx, "other", bhttp.AlgES256)
+		require.ErrorIs(t, err, bhttp.ErrUnknownKey)
+	})
+
+	t.Run("fails without keys", func(t *testing.T) {
+		_, err := bhttp.NewJWKS(srv.URL).Key(ctx, "new", bhttp.AlgES256)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, bhttp.ErrUnknownKey)
+	})
+}
+
+func TestJWKSConcurrentFetch(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	srv, fetches := jwksServer(t, map[string]any{"k1": key})
+	ctx := context.Background()
+
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(slow.Close)
+
+	lookup := func(set *bhttp.JWKS, done chan<- error) {
+		_, err := set.Key(ctx, "k1", bhttp.AlgES256)
+		done <- err
+	}
+
+	t.Run("waits for the fetch without a cached key", func(t *testing.T) {
+		set := bhttp.NewJWKS(slow.URL)
+		first, second := make(chan error), make(chan error)
+
+		go lookup(set, first)
+		<-entered
+		go lookup(set, second)
+
+		release <- struct{}{}
+		require.NoError(t, <-first)
+		require.NoError(t, <-second)
+		require.Equal(t, int64(1), fetches.Load())
+	})
+
+	t.Run("uses the cached key while refreshing", func(t *testing.T) {
+		set := bhttp.NewJWKS(slow.URL, bhttp.WithJWKSRefresh(10*time.Millisecond), bhttp.WithJWKSMinRefresh(0))
+		refreshed := make(chan error)
+
+		go lookup(set, refreshed)
+		<-entered
+		release <- struct{}{}
+		require.NoError(t, <-refreshed)
+
+		time.Sleep(20 * time.Millisecond)
+
+		go lookup(set, refreshed)
+		<-entered
+
+		_, err := set.Key(ctx, "k1", bhttp.AlgES256)
+		require.NoError(t, err)
+
+		release <- struct{}{}
+		require.NoError(t, <-refreshed)
+		require.Equal(t, int64(3), fetches.Load())
+	})
+}
+
+func TestJWKSFetchContext(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	srv, _ := jwksServer(t, map[string]any{"k1": key})
+
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(slow.Close)
+
+	lookup := func(ctx context.Context, set *bhttp.JWKS, done chan<- error) {
+		_, err := set.Key(ctx, "k1", bhttp.AlgES256)
+		done <- err
+	}
+
+	t.Run("outlives the request that started it", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+
+		go lookup(ctx, bhttp.NewJWKS(slow.URL), done)
+		<-entered
+		cancel()
+
+		release <- struct{}{}
+		require.NoError(t, <-done)
+	})
+
+	t.Run("is bounded by the timeout", func(t *testing.T) {
+		done := make(chan error)
+
+		go lookup(context.Background(), bhttp.NewJWKS(slow.URL, bhttp.WithJWKSFetchTimeout(10*time.Millisecond)), done)
+		<-entered
+		require.ErrorIs(t, <-done, context.DeadlineExceeded)
+
+		release <- struct{}{}
+	})
+}
+
+func TestHMACKey(t *testing.T) {
+	secret := []byte("secret")
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.JWTAuth(bhttp.HMACKey(secret)))
+	mux.HandleFunc("GET /me", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		_, err := fmt.Fprint(w, bhttp.JWTClaims(ctx).Subject)
+		return err
+	})
+
+	token := signJWT(t, secret, "", map[string]any{"sub": "bob", "exp": float64(time.Now().Unix()) + 60.5})
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "bearer "+token)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "bob", rec.Body.String())
+
+	rec, req = httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Basic Ym9iOnB3")
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+}
@@ -0,0 +1,181 @@
+package bhttp
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// RequestLimits bounds what a request may send. The zero value imposes no limits.
+type RequestLimits struct {
+	// BodyLimit is the maximum size of the request body in bytes. Zero means no limit, or, for the limits of
+	// a route, the global limit. A negative limit on a route removes the global limit.
+	BodyLimit int64
+	// ContentTypes lists the media types a request body may have, such as "application/json" or
+	// "image/*". Parameters like the charset are ignored. When empty, any content type is accepted, or, for
+	// the limits of a route, the global list.
+	ContentTypes []string
+	// RequireContentLength rejects requests with a body of unknown length, such as chunked uploads. A route
+	// can only switch it on.
+	RequireContentLength bool
+}
+
+// merge returns the limits with the settings of 'route' that are set.
+func (l RequestLimits) merge(route RequestLimits) RequestLimits {
+	if route.BodyLimit != 0 {
+		l.BodyLimit = max(route.BodyLimit, 0)
+	}
+
+	if len(route.ContentTypes) > 0 {
+		l.ContentTypes = route.ContentTypes
+	}
+
+	l.RequireContentLength = l.RequireContentLength || route.RequireContentLength
+
+	return l
+}
+
+// LimitOption configures the middleware returned by [LimitRequests].
+type LimitOption func(*limitOptions)
+
+type limitOptions struct {
+	routes map[string]RequestLimits
+}
+
+// RouteLimits overrides the settings that are set in 'limits' for the route with the given name, for
+// example to allow large uploads on a single route. Routes can also declare their limits with [Limits].
+func RouteLimits(name string, limits RequestLimits) LimitOption {
+	return func(o *limitOptions) { o.routes[name] = limits }
+}
+
+// limitsKey holds the limits that routes declare with [Limits].
+var limitsKey = NewMetaKey[RequestLimits]("request-limits") //nolint:gochecknoglobals
+
+// Limits declares the request limits of the route, overriding the settings of [LimitRequests] that are set
+// in 'limits'. It also works for unnamed routes:
+//
+//	mux.HandleFunc("PUT /avatar", putAvatar, bhttp.Limits(bhttp.RequestLimits{BodyLimit: 10 << 20}))
+func Limits(limits RequestLimits) RouteOption {
+	return Meta(limitsKey, limits)
+}
+
+// LimitRequests returns middleware that enforces 'limits' on every matched route, with the settings that a
+// route overrides through [RouteLimits] or [Limits]. Bodies larger than the limit are answered with
+// [CodeRequestEntityTooLarge]: upfront when the Content-Length is known, and otherwise once the handler
+// has read past the limit and returns an error. Bodies of a media type that is not allowed are answered
+// with [CodeUnsupportedMediaType], and bodies of unknown length with [CodeLengthRequired] when a length is
+// required. Requests that match no route have no handler to read their body, so they are left to be
+// answered as not found.
+func LimitRequests(limits RequestLimits, opts ...LimitOption) Middleware {
+	o := limitOptions{routes: map[string]RequestLimits{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			route := MatchedRoute(r.Context())
+			if route == nil {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			limits := limits
+			if override, ok := o.routes[route.Name]; ok && route.Name != "" {
+				limits = limits.merge(override)
+			}
+
+			if override, ok := limitsKey.Of(route); ok {
+				limits = limits.merge(override)
+			}
+
+			if err := limits.check(r); err != nil {
+				return err
+			}
+
+			if limits.BodyLimit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limits.BodyLimit)}
+
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.Body = body
+
+			err := next.ServeBareBHTTP(w, r2)
+			if err != nil && body.exceeded {
+				return bodyTooLargeError(limits.BodyLimit)
+			}
+
+			return err
+		})
+	}
+}
+
+// check returns the error for a request that does not satisfy the limits, before its body is read.
+func (l RequestLimits) check(r *http.Request) error {
+	if r.ContentLength == 0 {
+		return nil // no body to check.
+	}
+
+	if l.RequireContentLength && r.ContentLength < 0 {
+		return NewError(CodeLengthRequired, errors.New("request body must have a Content-Length"))
+	}
+
+	if l.BodyLimit > 0 && r.ContentLength > l.BodyLimit {
+		return bodyTooLargeError(l.BodyLimit)
+	}
+
+	if len(l.ContentTypes) > 0 && !mediaTypeAllowed(l.ContentTypes, r.Header.Get("Content-Type")) {
+		return NewError(CodeUnsupportedMediaType, errors.Newf("content type %q is not supported, expect one of: %s",
+			r.Header.Get("Content-Type"), strings.Join(l.ContentTypes, ", ")))
+	}
+
+	return nil
+}
+
+// mediaTypeAllowed reports whether the media type of content type 'ct' is in the 'allowed' list, which
+// may contain wildcards such as "image/*".
+func mediaTypeAllowed(allowed []string, ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range allowed {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mt, strings.ToLower(prefix)+"/") {
+				return true
+			}
+		} else if mt == strings.ToLower(allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bodyTooLargeError(limit int64) *Error {
+	return NewError(CodeRequestEntityTooLarge, errors.Newf("request body exceeds %d bytes", limit))
+}
+
+// limitedBody records whether reading the request body failed because it exceeded the limit, so that
+// the middleware can report it even when the handler wrapped or replaced the error.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+
+	return n, err //nolint:wrapcheck
+}
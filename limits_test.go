@@ -0,0 +1,99 @@
+package bhttp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedMux(t *testing.T) *bhttp.ServeMux {
+	t.Helper()
+
+	echo := func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return errors.Wrap(err, "read failed")
+		}
+
+		_, err = w.Write(data)
+
+		return err
+	}
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.LimitRequests(bhttp.RequestLimits{
+		BodyLimit:    8,
+		ContentTypes: []string{"application/json", "text/*"},
+	}, bhttp.RouteLimits("upload", bhttp.RequestLimits{BodyLimit: 16, ContentTypes: []string{"image/*"}})))
+	mux.HandleFunc("POST /echo", echo)
+	mux.HandleFunc("POST /upload", echo, bhttp.Name("upload"))
+	mux.HandleFunc("POST /strict", echo, bhttp.Limits(bhttp.RequestLimits{RequireContentLength: true}))
+	mux.HandleFunc("POST /unlimited", echo, bhttp.Limits(bhttp.RequestLimits{BodyLimit: -1}))
+
+	return mux
+}
+
+func TestLimitRequests(t *testing.T) {
+	mux := newLimitedMux(t)
+
+	for _, test := range []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		chunked     bool
+		wantCode    int
+		wantBody    string
+	}{
+		{"within limits", "/echo", "application/json; charset=utf-8", `{"a":1}`, false, http.StatusOK, `{"a":1}`},
+		{"wildcard media type", "/echo", "text/plain", `hello`, false, http.StatusOK, `hello`},
+		{"no body", "/echo", "", ``, false, http.StatusOK, ``},
+		{"too large", "/echo", "text/plain", `123456789`, false, http.StatusRequestEntityTooLarge,
+			"Request Entity Too Large: request body exceeds 8 bytes\n"},
+		{"too large chunked", "/upload", "image/png", strings.Repeat("x", 20), true, http.StatusRequestEntityTooLarge,
+			"Request Entity Too Large: request body exceeds 16 bytes\n"},
+		{"unsupported type", "/echo", "image/png", `png`, false, http.StatusUnsupportedMediaType,
+			"Unsupported Media Type: content type \"image/png\" is not supported, expect one of: application/json, text/*\n"},
+		{"length required", "/strict", "text/plain", `hello`, true, http.StatusLengthRequired,
+			"Length Required: request body must have a Content-Length\n"},
+		{"route override", "/upload", "image/png", `0123456789`, true, http.StatusOK, `0123456789`},
+		{"route override replaces types", "/upload", "text/plain", `hello`, false, http.StatusUnsupportedMediaType,
+			"Unsupported Media Type: content type \"text/plain\" is not supported, expect one of: image/*\n"},
+		{"route keeps global body limit", "/strict", "text/plain", `123456789`, false, http.StatusRequestEntityTooLarge,
+			"Request Entity Too Large: request body exceeds 8 bytes\n"},
+		{"route keeps global types", "/strict", "image/png", `png`, false, http.StatusUnsupportedMediaType,
+			"Unsupported Media Type: content type \"image/png\" is not supported, expect one of: application/json, text/*\n"},
+		{"route removes body limit", "/unlimited", "text/plain", strings.Repeat("x", 20), true, http.StatusOK,
+			strings.Repeat("x", 20)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+
+			if test.chunked {
+				req.ContentLength = -1
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, test.wantCode, rec.Code)
+			require.Equal(t, test.wantBody, rec.Body.String())
+		})
+	}
+
+	t.Run("unmatched passes through", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/other", strings.NewReader("x"))
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,50 @@
+package bhttp
+
+import "context"
+
+// MetaKey identifies a typed piece of route metadata, such as a cache policy or the owning team. Keys are
+// compared by identity, so they are best declared as package-level variables:
+//
+//	var Owner = bhttp.NewMetaKey[string]("owner")
+//
+//	mux.HandleFunc("GET /items", listItems, bhttp.Meta(Owner, "team-catalog"))
+type MetaKey[T any] struct {
+	name string
+}
+
+// NewMetaKey inits a metadata key. The name is only used for descriptions of the key.
+func NewMetaKey[T any](name string) *MetaKey[T] {
+	return &MetaKey[T]{name: name}
+}
+
+// String returns the name of the key.
+func (k *MetaKey[T]) String() string { return k.name }
+
+// Meta attaches the value for 'key' to the route. A later value for the same key replaces an earlier one.
+func Meta[T any](key *MetaKey[T], value T) RouteOption {
+	return func(c *routeConfig) {
+		if c.meta == nil {
+			c.meta = make(map[any]any)
+		}
+
+		c.meta[key] = value
+	}
+}
+
+// Of returns the value of the key for 'route', and whether the route has one.
+func (k *MetaKey[T]) Of(route *RouteInfo) (T, bool) {
+	var zero T
+	if route == nil {
+		return zero, false
+	}
+
+	v, ok := route.meta[k].(T)
+
+	return v, ok
+}
+
+// Value returns the value of the key for the route that matched the request, see [MatchedRoute]. It is
+// meant for middleware that behaves differently per route.
+func (k *MetaKey[T]) Value(ctx context.Context) (T, bool) {
+	return k.Of(MatchedRoute(ctx))
+}
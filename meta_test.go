@@ -0,0 +1,88 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+type cachePolicy struct {
+	MaxAge time.Duration
+}
+
+var (
+	cacheKey      = bhttp.NewMetaKey[cachePolicy]("cache")
+	ownerKey      = bhttp.NewMetaKey[string]("owner")
+	deprecatedKey = bhttp.NewMetaKey[time.Time]("deprecated")
+)
+
+func TestRouteMeta(t *testing.T) {
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.Use(func(next bhttp.BareHandler) bhttp.BareHandler {
+		return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+			if policy, ok := cacheKey.Value(r.Context()); ok {
+				w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+
+			if at, ok := deprecatedKey.Value(r.Context()); ok {
+				w.Header().Set("Sunset", at.Format(http.TimeFormat))
+			}
+
+			return next.ServeBareBHTTP(w, r)
+		})
+	})
+	mux.HandleFunc("GET /items", noop,
+		bhttp.Name("list-items"),
+		bhttp.Meta(cacheKey, cachePolicy{MaxAge: time.Second}),
+		bhttp.Meta(cacheKey, cachePolicy{MaxAge: time.Minute}),
+		bhttp.Meta(ownerKey, "team-catalog"))
+	mux.HandleFunc("GET /v1/items", noop, bhttp.Meta(deprecatedKey, sunset))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	t.Run("middleware reads metadata", func(t *testing.T) {
+		rec := serve("/items")
+		require.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"), "later values replace earlier ones")
+		require.Empty(t, rec.Header().Get("Sunset"))
+
+		rec = serve("/v1/items")
+		require.Empty(t, rec.Header().Get("Cache-Control"))
+		require.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+		require.Equal(t, http.StatusNotFound, serve("/other").Code)
+	})
+
+	t.Run("routes expose metadata", func(t *testing.T) {
+		routes := mux.Routes()
+
+		owner, ok := ownerKey.Of(&routes[0])
+		require.True(t, ok)
+		require.Equal(t, "team-catalog", owner)
+
+		_, ok = ownerKey.Of(&routes[1])
+		require.False(t, ok)
+
+		_, ok = ownerKey.Of(nil)
+		require.False(t, ok)
+		require.Equal(t, "owner", ownerKey.String())
+	})
+
+	t.Run("keys are compared by identity", func(t *testing.T) {
+		_, ok := bhttp.NewMetaKey[string]("owner").Of(&mux.Routes()[0])
+		require.False(t, ok)
+	})
+}
@@ -8,14 +8,14 @@ import (
 
 // Mount mounts a Handler on a sub-path pattern. The mounted handler receives
 // requests with the mount prefix stripped from the path.
-func (m *ServeMux) Mount(pattern string, handler Handler) {
-	m.MountBare(pattern, ToBare(handler))
+func (m *ServeMux) Mount(pattern string, handler Handler, opts ...RouteOption) {
+	m.MountBare(pattern, ToBare(handler), opts...)
 }
 
 // MountFunc mounts a HandlerFunc on a sub-path pattern. The mounted handler receives
 // requests with the mount prefix stripped from the path.
-func (m *ServeMux) MountFunc(pattern string, handler HandlerFunc) {
-	m.Mount(pattern, handler)
+func (m *ServeMux) MountFunc(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	m.Mount(pattern, handler, opts...)
 }
 
 // MountStd mounts a standard library [http.Handler] on a sub-path pattern. The mounted
@@ -23,28 +23,35 @@ func (m *ServeMux) MountFunc(pattern string, handler HandlerFunc) {
 // registered via [ServeMux.Use] is applied and sees the original path. See the
 // package-level section "Standard library handlers and error ownership" for details
 // on error handling behavior.
-func (m *ServeMux) MountStd(pattern string, handler http.Handler) {
+func (m *ServeMux) MountStd(pattern string, handler http.Handler, opts ...RouteOption) {
 	m.MountBare(pattern, BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
 		handler.ServeHTTP(w, r)
 		return nil
-	}))
+	}), opts...)
 }
 
 // MountBare mounts a BareHandler on a sub-path pattern. The mounted handler receives
 // requests with the mount prefix stripped from the path. Middleware registered via Use()
 // sees the original path; the strip happens after middleware.
-func (m *ServeMux) MountBare(pattern string, handler BareHandler) {
+//
+// Options apply to the whole subtree, so a [Policy] declared with them is enforced for,
+// and reported by [ServeMux.Routes] on, both registered patterns. A [Name] only names the
+// exact prefix, since a name refers to a single pattern.
+func (m *ServeMux) MountBare(pattern string, handler BareHandler, opts ...RouteOption) {
+	cfg := newRouteConfig(opts...)
 	method, path := splitMethodPattern(pattern)
 
 	stripped := stripPrefixBare(path, handler)
-	wrapped := wrapBare(stripped, m.middlewares.buffered...)
-	stdHandler := ToStd(wrapped, m.bufLimit, m.logs)
+	wrapped := wrapBare(stripped, m.routeMiddleware(pattern, cfg)...)
 
 	exact := method + path
 	subtree := method + path + "/"
 
-	m.handle(exact, stdHandler)
-	m.handle(subtree, stdHandler)
+	subtreeCfg := cfg
+	subtreeCfg.name = ""
+
+	m.handle(exact, wrapped, path, cfg)
+	m.handle(subtree, wrapped, path, subtreeCfg)
 }
 
 func splitMethodPattern(pattern string) (method, path string) {
@@ -73,8 +80,7 @@ func stripPrefixBare(prefix string, handler BareHandler) BareHandler {
 			}
 		}
 
-		r2 := new(http.Request)
-		*r2 = *r
+		r2 := r.WithContext(withMountPrefix(r.Context(), prefix))
 		r2.URL = new(url.URL)
 		*r2.URL = *r.URL
 		r2.URL.Path = p
@@ -0,0 +1,155 @@
+package bhttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/advdv/bhttp/openapi"
+	"github.com/cockroachdb/errors"
+)
+
+// operationDescriber is implemented by handlers that know more about their operation than the route
+// they are registered on, such as the types of their input and output.
+type operationDescriber interface {
+	describeOperation(op *openapi.Operation, gen *openapi.Generator)
+}
+
+// OpenAPI generates an OpenAPI 3.1 document from the routes registered so far. Paths and path parameters
+// are taken from the patterns, operation ids from the route names, and request and response schemas from
+// handlers created with [JSON] and typed routes. Mounted routes and routes without a method are not
+// included, and when several routes share a path and method only the first is described.
+func (m *ServeMux) OpenAPI(info openapi.Info) *openapi.Document {
+	doc := &openapi.Document{OpenAPI: openapi.Version, Info: info, Paths: map[string]map[string]*openapi.Operation{}}
+	gen := openapi.NewGenerator()
+
+	for _, route := range m.routes {
+		handler := m.handlers[route]
+		if _, ok := handler.(*openAPIHandler); ok || route.Mounted || route.Method == "" {
+			continue
+		}
+
+		path, method := openAPIPath(route.Segments), strings.ToLower(route.Method)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*openapi.Operation{}
+		}
+
+		if _, exists := doc.Paths[path][method]; exists {
+			continue
+		}
+
+		op := &openapi.Operation{OperationID: route.Name, Responses: map[string]*openapi.Response{}}
+		for _, seg := range route.Segments {
+			if seg.Wild && seg.Value != "" {
+				param := op.Parameter(seg.Value, "path")
+				param.Required = true
+				param.Schema = constraintSchema(seg.Constraint)
+			}
+		}
+
+		if d, ok := handler.(operationDescriber); ok {
+			d.describeOperation(op, gen)
+		}
+
+		if len(op.Responses) < 1 {
+			op.Responses["default"] = &openapi.Response{Description: "Unspecified response"}
+		}
+
+		doc.Paths[path][method] = op
+	}
+
+	if schemas := gen.Components(); len(schemas) > 0 {
+		doc.Components = &openapi.Components{Schemas: schemas}
+	}
+
+	return doc
+}
+
+// HandleOpenAPI serves the document generated by [ServeMux.OpenAPI] on 'pattern', as YAML when the path
+// ends in ".yaml" or ".yml" and as JSON otherwise. The document is generated when requested, so it also
+// describes routes registered after this one. The route itself is not part of the document.
+func (m *ServeMux) HandleOpenAPI(pattern string, info openapi.Info, opts ...RouteOption) {
+	m.Handle(pattern, &openAPIHandler{mux: m, info: info}, opts...)
+}
+
+// openAPIHandler serves the OpenAPI document of a mux.
+type openAPIHandler struct {
+	mux  *ServeMux
+	info openapi.Info
+}
+
+// ServeBHTTP implements the [Handler] interface.
+func (h *openAPIHandler) ServeBHTTP(_ context.Context, w ResponseWriter, r *http.Request) error {
+	doc := h.mux.OpenAPI(h.info)
+
+	encode, contentType := doc.JSON, "application/json"
+	if strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") {
+		encode, contentType = doc.YAML, "application/yaml"
+	}
+
+	data, err := encode()
+	if err != nil {
+		return errors.Wrap(err, "failed to encode openapi document")
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	_, err = w.Write(data)
+
+	return errors.Wrap(err, "failed to write openapi document")
+}
+
+// openAPIPath returns the OpenAPI path template of a route.
+func openAPIPath(segs []Segment) string {
+	var b strings.Builder
+
+	for _, seg := range segs {
+		switch {
+		case seg.Wild && seg.Value == "", !seg.Wild && seg.Value == "/":
+			b.WriteString("/") // trailing slash of a subtree or "{$}" pattern.
+		case seg.Wild:
+			b.WriteString("/{" + seg.Value + "}")
+		default:
+			b.WriteString("/" + seg.Value)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "/"
+	}
+
+	return b.String()
+}
+
+// constraintSchema returns the schema of a path parameter with the given wildcard constraint.
+func constraintSchema(constraint string) *openapi.Schema {
+	switch constraint {
+	case "":
+		return &openapi.Schema{Type: "string"}
+	case "int":
+		return &openapi.Schema{Type: "integer"}
+	case "uint":
+		return &openapi.Schema{Type: "integer", Minimum: new(float64)}
+	case "uuid":
+		return &openapi.Schema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &openapi.Schema{Type: "string", Pattern: "^[A-Za-z]+$"}
+	case "alnum":
+		return &openapi.Schema{Type: "string", Pattern: "^[A-Za-z0-9]+$"}
+	default:
+		return &openapi.Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+	}
+}
+
+// errorResponses adds the responses of the error codes to 'op'. Errors are rendered as plain text.
+func errorResponses(op *openapi.Operation, codes ...Code) {
+	for _, code := range codes {
+		op.Responses[strconv.Itoa(int(code))] = &openapi.Response{
+			Description: http.StatusText(int(code)),
+			Content: map[string]*openapi.MediaType{
+				"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+			},
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package openapi holds the types of an OpenAPI 3.1 document and generates JSON schemas for Go types by
+// reflection. Documents are usually generated from the routes of a [github.com/advdv/bhttp.ServeMux].
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// Document is the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"              yaml:"openapi"`
+	Info       Info                             `json:"info"                 yaml:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"                yaml:"paths"`
+	Components *Components                      `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"                 yaml:"title"`
+	Version     string `json:"version"               yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Operation describes a single method on a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"  yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"             yaml:"responses"`
+}
+
+// Parameter describes a single path, query, header or cookie parameter of an operation.
+type Parameter struct {
+	Name     string  `json:"name"               yaml:"name"`
+	In       string  `json:"in"                 yaml:"in"`
+	Required bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"   yaml:"schema,omitempty"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"            yaml:"content"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string                `json:"description"       yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// MediaType describes the body of a request or response in a given media type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// Components holds the schemas that are referenced from elsewhere in the document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// Parameter returns the parameter with the given name and location, adding it if it does not exist yet.
+func (op *Operation) Parameter(name, in string) *Parameter {
+	for _, param := range op.Parameters {
+		if param.Name == name && param.In == in {
+			return param
+		}
+	}
+
+	param := &Parameter{Name: name, In: in}
+	op.Parameters = append(op.Parameters, param)
+
+	return param
+}
+
+// JSON encodes the document as indented JSON. The output is deterministic, so it can be committed and
+// compared in CI to detect changes to the API.
+func (d *Document) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(d); err != nil {
+		return nil, errors.Wrap(err, "failed to encode json")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// YAML encodes the document as YAML. Like [Document.JSON], the output is deterministic.
+func (d *Document) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2) //nolint:mnd
+
+	if err := enc.Encode(d); err != nil {
+		return nil, errors.Wrap(err, "failed to encode yaml")
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close yaml encoder")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON schema as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"                 yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"                 yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty"               yaml:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"              yaml:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"              yaml:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"                yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"           yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"             yaml:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()              //nolint:gochecknoglobals
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()        //nolint:gochecknoglobals
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]() //nolint:gochecknoglobals
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()         //nolint:gochecknoglobals
+	invalidNameChars  = regexp.MustCompile(`[^A-Za-z0-9._-]+`)    //nolint:gochecknoglobals
+)
+
+// Generator generates schemas for Go types, following the rules of [encoding/json]. Named struct types
+// become components that are referenced with "$ref", which also supports recursive types.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// NewGenerator inits a generator without components.
+func NewGenerator() *Generator {
+	return &Generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// Components returns the schemas of the named types that have been generated so far, by name.
+func (g *Generator) Components() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of type 't'.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer && !t.Implements(jsonMarshalerType) && !t.Implements(textMarshalerType) {
+		return g.Schema(t.Elem())
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType), reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType), reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component returns the component name of named struct type 't', generating its schema the first time.
+func (g *Generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := invalidNameChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; taken {
+		name = invalidNameChars.ReplaceAllString(t.PkgPath()+"."+t.Name(), "_")
+	}
+
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // reserve the name, for recursive types.
+	*g.schemas[name] = *g.structSchema(t)
+
+	return name
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(schema, t)
+
+	return schema
+}
+
+// addFields adds the json fields of struct type 't' to 'schema', flattening embedded structs.
+func (g *Generator) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				g.addFields(schema, ft)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = g.Schema(field.Type)
+
+		optional := field.Type.Kind() == reflect.Pointer ||
+			strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		if !optional {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+type Base struct {
+	ID int64 `json:"id"`
+}
+
+type Node struct {
+	Base
+
+	Name     string           `json:"name"`
+	Note     *string          `json:"note"`
+	Tags     []string         `json:"tags,omitempty"`
+	Attrs    map[string]int   `json:"attrs,omitzero"`
+	Children []*Node          `json:"children"`
+	Created  time.Time        `json:"created"`
+	Addr     netip.Addr       `json:"addr"`
+	Raw      json.RawMessage  `json:"raw"`
+	Data     []byte           `json:"data"`
+	Inline   struct{ A bool } `json:"inline"`
+	Skipped  string           `json:"-"`
+	Default  uint8
+	hidden   string //nolint:unused
+}
+
+func TestGeneratorSchema(t *testing.T) {
+	gen := openapi.NewGenerator()
+
+	schema := gen.Schema(reflect.TypeFor[[]Node]())
+	require.Equal(t, &openapi.Schema{Type: "array", Items: &openapi.Schema{Ref: "#/components/schemas/Node"}}, schema)
+
+	data, err := json.Marshal(gen.Components())
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"Node": {
+			"type": "object",
+			"properties": {
+				"id": {"type": "integer", "format": "int64"},
+				"name": {"type": "string"},
+				"note": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"attrs": {"type": "object", "additionalProperties": {"type": "integer", "format": "int64"}},
+				"children": {"type": "array", "items": {"$ref": "#/components/schemas/Node"}},
+				"created": {"type": "string", "format": "date-time"},
+				"addr": {"type": "string"},
+				"raw": {},
+				"data": {"type": "string", "format": "byte"},
+				"inline": {"type": "object", "properties": {"A": {"type": "boolean"}}, "required": ["A"]},
+				"Default": {"type": "integer", "minimum": 0}
+			},
+			"required": ["id", "name", "children", "created", "addr", "raw", "data", "inline", "Default"]
+		}
+	}`, string(data))
+}
+
+func TestDocumentEncoding(t *testing.T) {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    openapi.Info{Title: "Test", Version: "1.0.0"},
+		Paths: map[string]map[string]*openapi.Operation{
+			"/b": {"get": {Responses: map[string]*openapi.Response{"200": {Description: "OK"}}}},
+			"/a": {"get": {Responses: map[string]*openapi.Response{"200": {Description: "OK"}}}},
+		},
+	}
+
+	data, err := doc.JSON()
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "openapi": "3.1.0",
+  "info": {
+    "title": "Test",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/a": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "OK"
+          }
+        }
+      }
+    },
+    "/b": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "OK"
+          }
+        }
+      }
+    }
+  }
+}
+`, string(data))
+
+	data, err = doc.YAML()
+	require.NoError(t, err)
+	require.Equal(t, `openapi: 3.1.0
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /a:
+    get:
+      responses:
+        "200":
+          description: OK
+  /b:
+    get:
+      responses:
+        "200":
+          description: OK
+`, string(data))
+}
@@ -0,0 +1,123 @@
+package bhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/advdv/bhttp/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+type apiUser struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiUserParams struct {
+	ID int64 `path:"id"`
+}
+
+func newOpenAPIMux() *bhttp.ServeMux {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.HandleOpenAPI("GET /openapi.json", openapi.Info{Title: "Users", Version: "1.0.0"})
+	mux.HandleOpenAPI("GET /openapi.yaml", openapi.Info{Title: "Users", Version: "1.0.0"})
+	mux.Handle("POST /users", bhttp.JSON(func(_ context.Context, in createUserInput) (apiUser, error) {
+		return apiUser{Name: in.Name}, nil
+	}, bhttp.WithJSONStatus(http.StatusCreated), bhttp.WithJSONErrors(bhttp.CodeConflict)), bhttp.Name("create-user"))
+	bhttp.Route[apiUserParams]("GET /users/{id}", bhttp.Name("get-user")).Handle(mux,
+		func(context.Context, bhttp.ResponseWriter, *http.Request, apiUserParams) error { return nil })
+	mux.HandleFunc("DELETE /users/{id:uint}/{$}", noop)
+	mux.HandleFunc("/anything", noop)
+	mux.MountFunc("/static", noop)
+
+	return mux
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := newOpenAPIMux().OpenAPI(openapi.Info{Title: "Users", Version: "1.0.0"})
+
+	data, err := doc.JSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"openapi": "3.1.0",
+		"info": {"title": "Users", "version": "1.0.0"},
+		"paths": {
+			"/users": {
+				"post": {
+					"operationId": "create-user",
+					"requestBody": {
+						"required": true,
+						"content": {"application/json": {"schema": {"$ref": "#/components/schemas/createUserInput"}}}
+					},
+					"responses": {
+						"201": {
+							"description": "Created",
+							"content": {"application/json": {"schema": {"$ref": "#/components/schemas/apiUser"}}}
+						},
+						"400": {"description": "Bad Request", "content": {"text/plain": {"schema": {"type": "string"}}}},
+						"409": {"description": "Conflict", "content": {"text/plain": {"schema": {"type": "string"}}}},
+						"413": {"description": "Request Entity Too Large", "content": {"text/plain": {"schema": {"type": "string"}}}},
+						"415": {"description": "Unsupported Media Type", "content": {"text/plain": {"schema": {"type": "string"}}}},
+						"422": {"description": "Unprocessable Entity", "content": {"text/plain": {"schema": {"type": "string"}}}}
+					}
+				}
+			},
+			"/users/{id}": {
+				"get": {
+					"operationId": "get-user",
+					"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}}],
+					"responses": {
+						"400": {"description": "Bad Request", "content": {"text/plain": {"schema": {"type": "string"}}}}
+					}
+				}
+			},
+			"/users/{id}/": {
+				"delete": {
+					"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 0}}],
+					"responses": {"default": {"description": "Unspecified response"}}
+				}
+			}
+		},
+		"components": {
+			"schemas": {
+				"apiUser": {
+					"type": "object",
+					"properties": {"id": {"type": "integer", "format": "int64"}, "name": {"type": "string"}},
+					"required": ["id", "name"]
+				},
+				"createUserInput": {
+					"type": "object",
+					"properties": {"name": {"type": "string"}},
+					"required": ["name"]
+				}
+			}
+		}
+	}`, string(data))
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	mux := newOpenAPIMux()
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.Equal(t, "Users", doc.Info.Title)
+	require.NotContains(t, doc.Paths, "/openapi.json")
+
+	rec, req = httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(rec.Body.String(), "openapi: 3.1.0\n"))
+}
@@ -0,0 +1,115 @@
+package bhttp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+)
+
+// PathInt returns the path value of wildcard 'name' as an int. It returns a [CodeBadRequest] error that
+// names the parameter when the value is not an integer.
+func PathInt(r *http.Request, name string) (int, error) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, paramError("path parameter", name, errors.New("must be an integer"))
+	}
+
+	return n, nil
+}
+
+// PathInt64 returns the path value of wildcard 'name' as an int64. It returns a [CodeBadRequest] error
+// that names the parameter when the value is not a 64-bit integer.
+func PathInt64(r *http.Request, name string) (int64, error) {
+	n, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		return 0, paramError("path parameter", name, errors.New("must be an integer"))
+	}
+
+	return n, nil
+}
+
+// PathUUID returns the path value of wildcard 'name' as a UUID. It returns a [CodeBadRequest] error that
+// names the parameter when the value is not a UUID.
+func PathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		return uuid.Nil, paramError("path parameter", name, errors.New("must be a UUID"))
+	}
+
+	return id, nil
+}
+
+// QueryInt returns the query parameter 'name' as an int, or 'def' when the parameter is absent or empty.
+// It returns a [CodeBadRequest] error that names the parameter when the value is not an integer.
+func QueryInt(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, paramError("query parameter", name, errors.New("must be an integer"))
+	}
+
+	return n, nil
+}
+
+// QueryBool returns the query parameter 'name' as a bool, or 'def' when the parameter is absent or empty.
+// It accepts the values understood by [strconv.ParseBool] and returns a [CodeBadRequest] error that
+// names the parameter otherwise.
+func QueryBool(r *http.Request, name string, def bool) (bool, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, paramError("query parameter", name, errors.New("must be a boolean"))
+	}
+
+	return b, nil
+}
+
+// QueryTime returns the query parameter 'name' as a time in RFC 3339 format, or 'def' when the parameter
+// is absent or empty. It returns a [CodeBadRequest] error that names the parameter when the value is
+// not a valid RFC 3339 time.
+func QueryTime(r *http.Request, name string, def time.Time) (time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, paramError("query parameter", name, errors.New("must be an RFC 3339 time"))
+	}
+
+	return t, nil
+}
+
+// HeaderDuration returns the header 'name' as a duration in the format of [time.ParseDuration], or 'def'
+// when the header is absent or empty. It returns a [CodeBadRequest] error that names the header when the
+// value is not a valid duration.
+func HeaderDuration(r *http.Request, name string, def time.Duration) (time.Duration, error) {
+	s := r.Header.Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, paramError("header", name, errors.New("must be a duration"))
+	}
+
+	return d, nil
+}
+
+// paramError returns the [CodeBadRequest] error for an invalid request parameter.
+func paramError(kind, name string, err error) *Error {
+	return NewError(CodeBadRequest, errors.Wrapf(err, "invalid %s %q", kind, name))
+}
@@ -0,0 +1,87 @@
+package bhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetPathValue("id", "42")
+	req.SetPathValue("uid", "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	req.SetPathValue("bad", "abc")
+
+	n, err := bhttp.PathInt(req, "id")
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+
+	n64, err := bhttp.PathInt64(req, "id")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), n64)
+
+	id, err := bhttp.PathUUID(req, "uid")
+	require.NoError(t, err)
+	require.Equal(t, uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), id)
+
+	_, err = bhttp.PathInt(req, "bad")
+	require.Equal(t, bhttp.CodeBadRequest, bhttp.CodeOf(err))
+	require.EqualError(t, err, `Bad Request: invalid path parameter "bad": must be an integer`)
+
+	_, err = bhttp.PathInt64(req, "missing")
+	require.Equal(t, bhttp.CodeBadRequest, bhttp.CodeOf(err))
+
+	_, err = bhttp.PathUUID(req, "bad")
+	require.EqualError(t, err, `Bad Request: invalid path parameter "bad": must be a UUID`)
+}
+
+func TestQueryParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?limit=10&all=true&since=2024-01-02T03:04:05Z&bad=x", nil)
+
+	limit, err := bhttp.QueryInt(req, "limit", 20)
+	require.NoError(t, err)
+	require.Equal(t, 10, limit)
+
+	limit, err = bhttp.QueryInt(req, "page", 20)
+	require.NoError(t, err)
+	require.Equal(t, 20, limit)
+
+	all, err := bhttp.QueryBool(req, "all", false)
+	require.NoError(t, err)
+	require.True(t, all)
+
+	since, err := bhttp.QueryTime(req, "since", time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), since)
+
+	_, err = bhttp.QueryInt(req, "bad", 0)
+	require.EqualError(t, err, `Bad Request: invalid query parameter "bad": must be an integer`)
+
+	_, err = bhttp.QueryBool(req, "bad", false)
+	require.EqualError(t, err, `Bad Request: invalid query parameter "bad": must be a boolean`)
+
+	_, err = bhttp.QueryTime(req, "bad", time.Time{})
+	require.EqualError(t, err, `Bad Request: invalid query parameter "bad": must be an RFC 3339 time`)
+}
+
+func TestHeaderDuration(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Timeout", "1.5s")
+	req.Header.Set("X-Bad", "soon")
+
+	d, err := bhttp.HeaderDuration(req, "X-Timeout", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, d)
+
+	d, err = bhttp.HeaderDuration(req, "X-Missing", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, d)
+
+	_, err = bhttp.HeaderDuration(req, "X-Bad", 0)
+	require.EqualError(t, err, `Bad Request: invalid header "X-Bad": must be a duration`)
+}
@@ -0,0 +1,88 @@
+package bhttp
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Principal is the authenticated caller of a request, as stored in the context by authentication
+// middleware such as [JWTAuth].
+type Principal interface {
+	HasScope(scope string) bool
+	HasRole(role string) bool
+}
+
+// WithPrincipal returns a context that carries the authenticated caller. Authentication middleware
+// calls it so that route policies can be enforced.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKeyPrincipal, p)
+}
+
+// PrincipalFrom returns the authenticated caller of the request, or nil if the request is not
+// authenticated.
+func PrincipalFrom(ctx context.Context) Principal {
+	p, _ := ctx.Value(ctxKeyPrincipal).(Principal)
+	return p
+}
+
+// Policy describes who may call a route. It is declared when the route is registered and can be
+// inspected through [RouteInfo], for example to list the public routes of a service.
+type Policy struct {
+	Public bool     // whether the route can be called without authentication.
+	Scopes []string // scopes that the principal must all have.
+	Roles  []string // roles of which the principal must have at least one.
+}
+
+// Restricted reports whether the route requires scopes or roles.
+func (p Policy) Restricted() bool { return len(p.Scopes) > 0 || len(p.Roles) > 0 }
+
+func (p Policy) clone() Policy {
+	p.Scopes, p.Roles = slices.Clone(p.Scopes), slices.Clone(p.Roles)
+	return p
+}
+
+// Require declares scopes that the principal must all have to call the route.
+func Require(scopes ...string) RouteOption {
+	return func(c *routeConfig) { c.policy.Scopes = append(c.policy.Scopes, scopes...) }
+}
+
+// RequireRole declares roles of which the principal must have at least one to call the route.
+func RequireRole(roles ...string) RouteOption {
+	return func(c *routeConfig) { c.policy.Roles = append(c.policy.Roles, roles...) }
+}
+
+// Public declares that the route can be called without authentication. Authentication middleware such
+// as [JWTAuth] lets requests without credentials through to public routes.
+func Public() RouteOption {
+	return func(c *routeConfig) { c.policy.Public = true }
+}
+
+// enforcePolicy returns the middleware that the mux applies inside all other middleware of a restricted
+// route, so that the policy holds no matter which authentication middleware is in use. Requests without
+// a principal get a [CodeUnauthorized] error, principals that lack a scope or role a [CodeForbidden]
+// error.
+func enforcePolicy(policy Policy) Middleware {
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			p := PrincipalFrom(r.Context())
+			if p == nil {
+				return NewError(CodeUnauthorized, errors.New("authentication required"))
+			}
+
+			for _, scope := range policy.Scopes {
+				if !p.HasScope(scope) {
+					return NewError(CodeForbidden, errors.Newf("missing scope %q", scope))
+				}
+			}
+
+			if len(policy.Roles) > 0 && !slices.ContainsFunc(policy.Roles, p.HasRole) {
+				return NewError(CodeForbidden, errors.Newf("missing one of roles %q", policy.Roles))
+			}
+
+			return next.ServeBareBHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// testPrincipal grants the scopes and roles it is created with.
+type testPrincipal struct{ scopes, roles []string }
+
+func (p testPrincipal) HasScope(s string) bool { return slices.Contains(p.scopes, s) }
+func (p testPrincipal) HasRole(r string) bool  { return slices.Contains(p.roles, r) }
+
+// testAuth authenticates requests with an "X-User" header, granting the scopes and roles listed in the
+// "X-Scopes" and "X-Roles" headers.
+func testAuth(next bhttp.BareHandler) bhttp.BareHandler {
+	return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+		if r.Header.Get("X-User") == "" {
+			return next.ServeBareBHTTP(w, r)
+		}
+
+		ctx := bhttp.WithPrincipal(r.Context(), testPrincipal{
+			scopes: strings.Fields(r.Header.Get("X-Scopes")),
+			roles:  strings.Fields(r.Header.Get("X-Roles")),
+		})
+
+		return next.ServeBareBHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func TestRoutePolicies(t *testing.T) {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.Use(testAuth)
+	mux.HandleFunc("GET /health", noop, bhttp.Name("health"), bhttp.Public())
+	mux.HandleFunc("GET /items", noop, bhttp.Name("list-items"))
+	mux.HandleFunc("DELETE /items/{id}", noop, bhttp.Name("delete-item"), bhttp.Require("items:read", "items:write"))
+	mux.HandleFunc("POST /admin", noop, bhttp.RequireRole("admin", "owner"))
+
+	serve := func(method, path, scopes, roles string, user bool) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(method, path, nil)
+		if user {
+			req.Header.Set("X-User", "alice")
+		}
+
+		req.Header.Set("X-Scopes", scopes)
+		req.Header.Set("X-Roles", roles)
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("unrestricted routes", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/health", "", "", false).Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/items", "", "", false).Code)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		rec := serve(http.MethodDelete, "/items/1", "", "", false)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "Unauthorized: authentication required\n", rec.Body.String())
+
+		rec = serve(http.MethodDelete, "/items/1", "items:read", "", true)
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		require.Equal(t, "Forbidden: missing scope \"items:write\"\n", rec.Body.String())
+
+		require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/items/1", "items:write items:read", "", true).Code)
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/admin", "", "viewer", true)
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		require.Equal(t, "Forbidden: missing one of roles [\"admin\" \"owner\"]\n", rec.Body.String())
+
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/admin", "", "viewer owner", true).Code)
+	})
+
+	t.Run("introspection", func(t *testing.T) {
+		var public, restricted []string
+		for _, route := range mux.Routes() {
+			switch {
+			case route.Policy.Public:
+				public = append(public, route.Pattern)
+			case route.Policy.Restricted():
+				restricted = append(restricted, route.Pattern)
+			}
+		}
+
+		require.Equal(t, []string{"GET /health"}, public)
+		require.Equal(t, []string{"DELETE /items/{id}", "POST /admin"}, restricted)
+		require.Equal(t, []string{"items:read", "items:write"}, mux.Routes()[2].Policy.Scopes)
+	})
+
+	t.Run("public and restricted", func(t *testing.T) {
+		require.PanicsWithValue(t, "bhttp: route GET /x cannot be both public and restricted", func() {
+			bhttp.NewServeMux().HandleFunc("GET /x", noop, bhttp.Public(), bhttp.Require("x"))
+		})
+	})
+}
+
+func TestMountPolicy(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(testAuth)
+	mux.MountStd("/admin", http.NotFoundHandler(), bhttp.Name("admin"), bhttp.RequireRole("admin"))
+
+	for _, path := range []string{"/admin", "/admin/users"} {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User", "alice")
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code, path)
+	}
+
+	routes := mux.Routes()
+	require.Len(t, routes, 2)
+	require.Equal(t, "admin", routes[0].Name)
+	require.Empty(t, routes[1].Name)
+
+	for _, route := range routes {
+		require.Equal(t, []string{"admin"}, route.Policy.Roles, route.Pattern)
+	}
+
+	loc, err := mux.Reverse("admin")
+	require.NoError(t, err)
+	require.Equal(t, "/admin", loc)
+}
+
+func TestRoutePoliciesWithJWT(t *testing.T) {
+	secret := []byte("secret")
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.JWTAuth(bhttp.HMACKey(secret)))
+	mux.HandleFunc("GET /health", noop, bhttp.Public())
+	mux.HandleFunc("GET /reports", noop, bhttp.RequireRole("auditor"))
+
+	serve := func(path, token string) int {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	exp := float64(time.Now().Add(time.Minute).Unix())
+
+	require.Equal(t, http.StatusOK, serve("/health", ""))
+	require.Equal(t, http.StatusUnauthorized, serve("/reports", ""))
+	require.Equal(t, http.StatusForbidden, serve("/reports", signJWT(t, secret, "", map[string]any{"exp": exp})))
+	require.Equal(t, http.StatusOK, serve("/reports", signJWT(t, secret, "", map[string]any{
+		"exp": exp, "roles": "auditor",
+	})))
+}
@@ -0,0 +1,343 @@
+package bhttp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Rate is the number of requests that are allowed per period.
+type Rate struct {
+	Requests int
+	Period   time.Duration
+}
+
+// RateLimitResult is the outcome of a [RateLimiter] decision.
+type RateLimitResult struct {
+	Allowed    bool          // whether the request may proceed.
+	Limit      int           // the number of requests allowed per period.
+	Remaining  int           // the number of requests that are still allowed right now.
+	Reset      time.Duration // the time until the full quota is available again.
+	RetryAfter time.Duration // the time until the next request is allowed, zero when allowed.
+}
+
+// RateLimiter decides whether the request identified by 'key' may proceed at the given rate. The
+// in-memory limiters only limit a single instance; implement this interface on top of a shared store to
+// limit across instances.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc identifies the client of a request, for example by its IP address or API key. An
+// empty key exempts the request from the limit.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// KeyByRemoteIP identifies clients by the IP address of the connection.
+func KeyByRemoteIP() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+
+		return host
+	}
+}
+
+// KeyByForwardedIP identifies clients by the "X-Forwarded-For" header, for servers behind 'trustedProxies'
+// proxies that each append the address they received the request from, such as API Gateway or an ALB (1).
+// The client controls the leading entries of the header, so the address is taken that many entries from
+// the right. Requests with fewer entries, or without the header, fall back to the address of the
+// connection.
+func KeyByForwardedIP(trustedProxies int) RateLimitKeyFunc {
+	if trustedProxies < 1 {
+		panic("bhttp: KeyByForwardedIP requires at least one trusted proxy")
+	}
+
+	return func(r *http.Request) string {
+		var addrs []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			for addr := range strings.SplitSeq(v, ",") {
+				addrs = append(addrs, strings.TrimSpace(addr))
+			}
+		}
+
+		if len(addrs) >= trustedProxies {
+			if ip := addrs[len(addrs)-trustedProxies]; ip != "" {
+				return ip
+			}
+		}
+
+		return KeyByRemoteIP()(r)
+	}
+}
+
+// KeyByHeader identifies clients by the value of a header, such as an API key. Requests without the
+// header are not limited.
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// RateLimitRule declares the rate for a set of routes.
+type RateLimitRule struct {
+	// Name identifies the rule in the keys passed to the limiter. It defaults to the position of the rule,
+	// so set it when rules are reordered while a shared limiter keeps its state.
+	Name string
+	// Routes lists the names of the routes the rule applies to. The routes share the same quota, so a
+	// rule with several routes limits them as a group. A rule without routes applies to all routes that
+	// no other rule lists.
+	Routes []string
+	// Rate is the number of requests that are allowed per period, per client.
+	Rate Rate
+	// Key identifies the client. It defaults to [KeyByRemoteIP].
+	Key RateLimitKeyFunc
+}
+
+// LimitRate returns middleware that limits the rate of requests per client and per rule. Requests over
+// the limit are answered with [CodeTooManyRequests] and a "Retry-After" header. All limited responses
+// carry "RateLimit-Limit", "RateLimit-Remaining" and "RateLimit-Reset" headers, also when the handler
+// fails. Requests that match no
+// route, or no rule, are not limited. It panics when a rule does not allow at least one request per
+// positive period.
+func LimitRate(limiter RateLimiter, rules ...RateLimitRule) Middleware {
+	rules = slices.Clone(rules) // defaults are filled in without changing the caller's rules.
+	for i := range rules {
+		if rules[i].Rate.Requests < 1 || rules[i].Rate.Period <= 0 {
+			panic(fmt.Sprintf("bhttp: invalid rate for rate limit rule %d: %d requests per %s",
+				i, rules[i].Rate.Requests, rules[i].Rate.Period))
+		}
+
+		if rules[i].Name == "" {
+			rules[i].Name = "rule-" + strconv.Itoa(i)
+		}
+
+		if rules[i].Key == nil {
+			rules[i].Key = KeyByRemoteIP()
+		}
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			route := MatchedRoute(r.Context())
+			if route == nil {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			rule := matchRateLimitRule(rules, route.Name)
+			if rule == nil {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			client := rule.Key(r)
+			if client == "" {
+				return next.ServeBareBHTTP(w, r)
+			}
+
+			res, err := limiter.Allow(r.Context(), rule.Name+":"+client, rule.Rate)
+			if err != nil {
+				return errors.Wrap(err, "failed to check rate limit")
+			}
+
+			if !res.Allowed {
+				err := NewError(CodeTooManyRequests, errors.New("rate limit exceeded"))
+				setRateLimitHeaders(err.Header(), res)
+				err.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+
+				return err
+			}
+
+			setRateLimitHeaders(w.Header(), res)
+			PreserveHeaders(w, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset")
+
+			return next.ServeBareBHTTP(w, r)
+		})
+	}
+}
+
+// matchRateLimitRule returns the rule that lists the route, or else the first rule without routes.
+func matchRateLimitRule(rules []RateLimitRule, name string) *RateLimitRule {
+	var fallback *RateLimitRule
+
+	for i, rule := range rules {
+		switch {
+		case len(rule.Routes) < 1 && fallback == nil:
+			fallback = &rules[i]
+		case name != "" && slices.Contains(rule.Routes, name):
+			return &rules[i]
+		}
+	}
+
+	return fallback
+}
+
+func setRateLimitHeaders(h http.Header, res RateLimitResult) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// memoryRateLimiter holds the state that both in-memory limiters share: a map of per-key state that is
+// swept of expired entries once in a while.
+type memoryRateLimiter[S any] struct {
+	mu        sync.Mutex
+	states    map[string]*S
+	expires   map[string]time.Time
+	lastSweep time.Time
+}
+
+// state returns the state of 'key', creating it when it does not exist. It must be called with the lock
+// held.
+func (l *memoryRateLimiter[S]) state(key string, now time.Time) *S {
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, expires := range l.expires {
+			if now.After(expires) {
+				delete(l.states, k)
+				delete(l.expires, k)
+			}
+		}
+
+		l.lastSweep = now
+	}
+
+	st, ok := l.states[key]
+	if !ok {
+		st = new(S)
+		l.states[key] = st
+	}
+
+	return st
+}
+
+// TokenBucketLimiter is an in-memory [RateLimiter] that allows bursts of up to the full rate, and
+// replenishes the quota continuously.
+type TokenBucketLimiter struct {
+	memoryRateLimiter[tokenBucket]
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter inits an in-memory token bucket limiter.
+func NewTokenBucketLimiter() *TokenBucketLimiter {
+	return &TokenBucketLimiter{memoryRateLimiter[tokenBucket]{
+		states: map[string]*tokenBucket{}, expires: map[string]time.Time{},
+	}}
+}
+
+// Allow implements [RateLimiter].
+func (l *TokenBucketLimiter) Allow(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	now := time.Now()
+	capacity, perSecond := float64(rate.Requests), float64(rate.Requests)/rate.Period.Seconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket := l.state(key, now)
+	if bucket.last.IsZero() {
+		bucket.tokens = capacity
+	} else {
+		bucket.tokens = min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*perSecond)
+	}
+
+	bucket.last = now
+
+	res := RateLimitResult{Limit: rate.Requests}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - bucket.tokens) / perSecond)
+	}
+
+	res.Remaining = int(bucket.tokens)
+	res.Reset = seconds((capacity - bucket.tokens) / perSecond)
+	l.expires[key] = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// SlidingWindowLimiter is an in-memory [RateLimiter] that counts the requests of the last period,
+// approximated by weighing the count of the previous fixed window. Unlike [TokenBucketLimiter] the quota
+// does not replenish while a client keeps sending requests.
+type SlidingWindowLimiter struct {
+	memoryRateLimiter[slidingWindow]
+}
+
+type slidingWindow struct {
+	start      time.Time // start of the current window.
+	curr, prev int
+}
+
+// NewSlidingWindowLimiter inits an in-memory sliding window limiter.
+func NewSlidingWindowLimiter() *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{memoryRateLimiter[slidingWindow]{
+		states: map[string]*slidingWindow{}, expires: map[string]time.Time{},
+	}}
+}
+
+// Allow implements [RateLimiter].
+func (l *SlidingWindowLimiter) Allow(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	win := l.state(key, now)
+
+	switch elapsed := now.Sub(win.start); {
+	case win.start.IsZero() || elapsed >= 2*rate.Period:
+		win.start, win.curr, win.prev = now, 0, 0
+	case elapsed >= rate.Period:
+		win.start, win.curr, win.prev = win.start.Add(rate.Period), 0, win.curr
+	}
+
+	elapsed := now.Sub(win.start)
+	weight := 1 - elapsed.Seconds()/rate.Period.Seconds()
+	count := float64(win.prev)*weight + float64(win.curr)
+
+	res := RateLimitResult{Limit: rate.Requests, Reset: rate.Period - elapsed}
+	if count+1 <= float64(rate.Requests) {
+		win.curr++
+		count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = slidingRetryAfter(win, rate, elapsed)
+	}
+
+	res.Remaining = max(0, rate.Requests-int(math.Ceil(count)))
+	l.expires[key] = win.start.Add(2 * rate.Period)
+
+	return res, nil
+}
+
+// slidingRetryAfter returns the time until the weighted count leaves room for another request.
+func slidingRetryAfter(win *slidingWindow, rate Rate, elapsed time.Duration) time.Duration {
+	room := float64(rate.Requests - 1 - win.curr)
+	if win.prev == 0 || room < 0 {
+		return rate.Period - elapsed // only the next window has room.
+	}
+
+	// solve prev * (1 - (elapsed+t)/period) + curr + 1 <= requests for t.
+	t := rate.Period.Seconds()*(1-room/float64(win.prev)) - elapsed.Seconds()
+
+	return max(seconds(t), 0)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
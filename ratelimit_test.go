@@ -0,0 +1,145 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitRate(t *testing.T) {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.LimitRate(bhttp.NewTokenBucketLimiter(),
+		bhttp.RateLimitRule{
+			Routes: []string{"create-order", "cancel-order"},
+			Rate:   bhttp.Rate{Requests: 2, Period: time.Hour},
+			Key:    bhttp.KeyByHeader("X-Api-Key"),
+		},
+		bhttp.RateLimitRule{Rate: bhttp.Rate{Requests: 3, Period: time.Minute}},
+	))
+	mux.HandleFunc("POST /orders", noop, bhttp.Name("create-order"))
+	mux.HandleFunc("DELETE /orders/{id}", noop, bhttp.Name("cancel-order"))
+	mux.HandleFunc("GET /orders", noop, bhttp.Name("list-orders"))
+	mux.HandleFunc("PUT /orders/{id}", func(context.Context, bhttp.ResponseWriter, *http.Request) error {
+		return bhttp.NewError(bhttp.CodeConflict, errors.New("order is shipped"))
+	}, bhttp.Name("update-order"))
+
+	serve := func(method, path, apiKey, remoteAddr string) *httptest.ResponseRecorder {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+
+		mux.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("limits a group of routes per key", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/orders", "key-a", "1.1.1.1:1")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "1800", rec.Header().Get("RateLimit-Reset"))
+
+		require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/orders/1", "key-a", "1.1.1.1:1").Code)
+
+		rec = serve(http.MethodPost, "/orders", "key-a", "1.1.1.1:1")
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		require.Equal(t, "Too Many Requests: rate limit exceeded\n", rec.Body.String())
+		require.Equal(t, "1800", rec.Header().Get("Retry-After"))
+		require.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders", "key-b", "1.1.1.1:1").Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders", "", "1.1.1.1:1").Code, "no key, no limit")
+	})
+
+	t.Run("default rule limits other routes per ip", func(t *testing.T) {
+		for range 3 {
+			require.Equal(t, http.StatusOK, serve(http.MethodGet, "/orders", "", "2.2.2.2:1").Code)
+		}
+
+		require.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/orders", "", "2.2.2.2:2").Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/orders", "", "3.3.3.3:1").Code)
+	})
+
+	t.Run("failed requests keep the headers", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/orders/1", "", "4.4.4.4:1")
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Equal(t, "3", rec.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "2", rec.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "20", rec.Header().Get("RateLimit-Reset"))
+	})
+
+	t.Run("unmatched requests are not limited", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/other", "", "2.2.2.2:1").Code)
+	})
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	ctx, limiter := context.Background(), bhttp.NewSlidingWindowLimiter()
+	rate := bhttp.Rate{Requests: 2, Period: 50 * time.Millisecond}
+
+	for i := range 2 {
+		res, err := limiter.Allow(ctx, "k", rate)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, 1-i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "k", rate)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Positive(t, res.RetryAfter)
+	require.LessOrEqual(t, res.RetryAfter, rate.Period)
+
+	time.Sleep(res.RetryAfter + 25*time.Millisecond)
+
+	res, err = limiter.Allow(ctx, "k", rate)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+}
+
+func TestKeyByForwardedIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:9000"
+	require.Equal(t, "127.0.0.1", bhttp.KeyByForwardedIP(1)(req))
+
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	require.Equal(t, "203.0.113.7", bhttp.KeyByForwardedIP(1)(req))
+	require.Equal(t, "198.51.100.1", bhttp.KeyByForwardedIP(2)(req))
+	require.Equal(t, "127.0.0.1", bhttp.KeyByForwardedIP(3)(req))
+
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	require.Equal(t, "203.0.113.7", bhttp.KeyByForwardedIP(2)(req))
+
+	require.Panics(t, func() { bhttp.KeyByForwardedIP(0) })
+}
+
+func TestLimitRateInvalidRule(t *testing.T) {
+	for _, rate := range []bhttp.Rate{
+		{Requests: 0, Period: time.Minute},
+		{Requests: 10, Period: 0},
+		{Requests: 10, Period: -time.Second},
+	} {
+		require.Panics(t, func() {
+			bhttp.LimitRate(bhttp.NewTokenBucketLimiter(), bhttp.RateLimitRule{Rate: rate})
+		})
+	}
+}
+
+func TestLimitRateKeepsCallerRules(t *testing.T) {
+	rules := []bhttp.RateLimitRule{{Rate: bhttp.Rate{Requests: 1, Period: time.Minute}}}
+	bhttp.LimitRate(bhttp.NewTokenBucketLimiter(), rules...)
+
+	require.Empty(t, rules[0].Name)
+	require.Nil(t, rules[0].Key)
+}
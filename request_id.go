@@ -0,0 +1,157 @@
+package bhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header that carries the request id.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength limits the length of request ids that are accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestIDOption configures [RequestID].
+type RequestIDOption func(*requestIDOptions)
+
+type requestIDOptions struct {
+	header   string
+	generate func(r *http.Request) string
+}
+
+// WithRequestIDHeader sets the header that carries the request id, it defaults to [DefaultRequestIDHeader].
+func WithRequestIDHeader(name string) RequestIDOption {
+	return func(o *requestIDOptions) { o.header = name }
+}
+
+// WithRequestIDGenerator sets the function that provides the id of requests that do not carry a valid one,
+// for example an id that the platform assigned to the invocation. When it returns the empty string, a
+// random id is generated.
+func WithRequestIDGenerator(fn func(r *http.Request) string) RequestIDOption {
+	return func(o *requestIDOptions) { o.generate = fn }
+}
+
+// RequestID returns middleware that identifies every request with an id, for correlating logs and errors
+// across services. It accepts the id that the client sent in the header, as long as it is at most 128
+// printable ASCII characters, and otherwise generates one. The id is available to handlers through
+// [RequestIDFrom], is echoed in the response header, also on error responses, and is appended to the body
+// of error responses that [ToStd] renders. Outbound requests carry it when they are sent through a
+// transport wrapped with [PropagateRequestID].
+func RequestID(opts ...RequestIDOption) Middleware {
+	o := requestIDOptions{header: DefaultRequestIDHeader}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			id := r.Header.Get(o.header)
+			if !validRequestID(id) {
+				id = ""
+				if o.generate != nil {
+					id = o.generate(r)
+				}
+
+				if id == "" {
+					id = newRequestID()
+				}
+			}
+
+			w.Header().Set(o.header, id)
+			PreserveHeaders(w, o.header)
+
+			ctx := context.WithValue(r.Context(), ctxKeyRequestID, &requestIDState{id: id, header: o.header})
+			if err := next.ServeBareBHTTP(w, r.WithContext(ctx)); err != nil {
+				return &requestIDError{err: err, id: id}
+			}
+
+			return nil
+		})
+	}
+}
+
+// requestIDState is what [RequestID] makes available through the context.
+type requestIDState struct {
+	id     string
+	header string
+}
+
+// RequestIDFrom returns the id of the request, or the empty string if the request was not handled by the
+// [RequestID] middleware.
+func RequestIDFrom(ctx context.Context) string {
+	state, _ := ctx.Value(ctxKeyRequestID).(*requestIDState)
+	if state == nil {
+		return ""
+	}
+
+	return state.id
+}
+
+// WithRequestID returns a copy of ctx that carries the request id, in the default header, for example to
+// propagate it from work that is not handled by the [RequestID] middleware, or in tests.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeyRequestID, &requestIDState{id: id, header: DefaultRequestIDHeader})
+}
+
+// PropagateRequestID wraps the transport so that outbound requests carry the id of the request in their
+// context, in the header that the [RequestID] middleware read it from. Requests that already set the
+// header are sent as is.
+func PropagateRequestID(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return requestIDTransport{base: base}
+}
+
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state, _ := req.Context().Value(ctxKeyRequestID).(*requestIDState)
+	if state == nil || req.Header.Get(state.header) != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context()) // a round tripper must not modify the request.
+	req.Header.Set(state.header, state.id)
+
+	return t.base.RoundTrip(req)
+}
+
+// requestIDError records the id of the request that failed, so that [ToStd] can include it in the error
+// response.
+type requestIDError struct {
+	err error
+	id  string
+}
+
+func (e *requestIDError) Error() string { return e.err.Error() }
+func (e *requestIDError) Unwrap() error { return e.err }
+
+// validRequestID reports whether an id that was received from a client is safe to use in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random request id.
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
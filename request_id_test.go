@@ -0,0 +1,161 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func requestIDMux(opts ...bhttp.RequestIDOption) *bhttp.ServeMux {
+	return requestIDRoutes(bhttp.RequestID(opts...))
+}
+
+// requestIDRoutes registers the test routes behind the given middleware.
+func requestIDRoutes(mw ...bhttp.Middleware) *bhttp.ServeMux {
+	mux := bhttp.NewServeMux()
+	mux.Use(mw...)
+	mux.HandleFunc("GET /id", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		_, err := fmt.Fprint(w, bhttp.RequestIDFrom(ctx))
+		return err
+	})
+	mux.HandleFunc("GET /fail", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		fmt.Fprint(w, "partial")
+		return bhttp.NewError(bhttp.CodeConflict, errors.New("already exists"))
+	})
+	mux.HandleFunc("GET /crash", func(context.Context, bhttp.ResponseWriter, *http.Request) error {
+		return errors.New("boom")
+	})
+
+	return mux
+}
+
+func serveRequestID(mux http.Handler, path, id string) *httptest.ResponseRecorder {
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)
+	if id != "" {
+		req.Header.Set(bhttp.DefaultRequestIDHeader, id)
+	}
+
+	mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRequestID(t *testing.T) {
+	mux := requestIDMux()
+
+	t.Run("accepts incoming id", func(t *testing.T) {
+		rec := serveRequestID(mux, "/id", "abc-123")
+		require.Equal(t, "abc-123", rec.Body.String())
+		require.Equal(t, "abc-123", rec.Header().Get(bhttp.DefaultRequestIDHeader))
+	})
+
+	t.Run("generates missing or invalid ids", func(t *testing.T) {
+		for _, id := range []string{"", "with space", "line\nbreak", strings.Repeat("x", 129)} {
+			rec := serveRequestID(mux, "/id", id)
+			require.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), rec.Body.String())
+			require.Equal(t, rec.Body.String(), rec.Header().Get(bhttp.DefaultRequestIDHeader))
+		}
+
+		require.NotEqual(t, serveRequestID(mux, "/id", "").Body.String(), serveRequestID(mux, "/id", "").Body.String())
+	})
+
+	t.Run("error responses", func(t *testing.T) {
+		rec := serveRequestID(mux, "/fail", "abc-123")
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Equal(t, "abc-123", rec.Header().Get(bhttp.DefaultRequestIDHeader))
+		require.Equal(t, "Conflict: already exists (request id: abc-123)\n", rec.Body.String())
+
+		rec = serveRequestID(mux, "/crash", "abc-123")
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.Equal(t, "Internal Server Error (request id: abc-123)\n", rec.Body.String())
+
+		rec = serveRequestID(mux, "/other", "abc-123")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "abc-123", rec.Header().Get(bhttp.DefaultRequestIDHeader))
+		require.Contains(t, rec.Body.String(), "(request id: abc-123)")
+	})
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	mux := requestIDMux(
+		bhttp.WithRequestIDHeader("X-Correlation-Id"),
+		bhttp.WithRequestIDGenerator(func(r *http.Request) string { return r.URL.Query().Get("invocation") }))
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/id?invocation=inv-1", nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "inv-1", rec.Body.String())
+	require.Equal(t, "inv-1", rec.Header().Get("X-Correlation-Id"))
+
+	rec, req = httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/id?invocation=inv-1", nil)
+	req.Header.Set("X-Correlation-Id", "client-1")
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "client-1", rec.Body.String(), "incoming ids take precedence")
+
+	rec = serveRequestID(mux, "/id", "")
+	require.Len(t, rec.Body.String(), 32, "empty generator result falls back to a random id")
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	var received []string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("X-Correlation-Id")+"|"+r.Header.Get(bhttp.DefaultRequestIDHeader))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: bhttp.PropagateRequestID(nil)}
+
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.RequestID(bhttp.WithRequestIDHeader("X-Correlation-Id")))
+	mux.HandleFunc("GET /call", func(ctx context.Context, _ bhttp.ResponseWriter, _ *http.Request) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	})
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/call", nil)
+	req.Header.Set("X-Correlation-Id", "abc-123")
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req, err := http.NewRequestWithContext(bhttp.WithRequestID(context.Background(), "job-1"), http.MethodGet, upstream.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, upstream.URL, nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	require.Equal(t, []string{"abc-123|", "|job-1", "|"}, received)
+}
+
+func TestRequestIDBehindMount(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.RequestID())
+	mux.MountStd("/api", requestIDRoutes())
+
+	rec := serveRequestID(mux, "/api/fail", "abc-123")
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, "abc-123", rec.Header().Get(bhttp.DefaultRequestIDHeader))
+	require.Equal(t, "Conflict: already exists (request id: abc-123)\n", rec.Body.String())
+}
@@ -3,6 +3,7 @@ package bhttp
 import (
 	"bytes"
 	"net/http"
+	"slices"
 	"sync"
 
 	"github.com/cockroachdb/errors"
@@ -22,6 +23,7 @@ type ResponseBuffer struct {
 	headerFlushed     bool
 	bodyFlushed       bool
 	unflushableHeader http.Header
+	preserved         []string // canonical keys of headers that survive a reset.
 }
 
 // responseBufferPool allows us to reuse some ResponseBuffer objects to
@@ -57,6 +59,7 @@ func (w *ResponseBuffer) Free() {
 	w.headerFlushed = false
 	w.bodyFlushed = false
 	w.unflushableHeader = nil
+	w.preserved = w.preserved[:0]
 	responseBufferPool.Put(w)
 }
 
@@ -89,14 +92,18 @@ func (w *ResponseBuffer) Header() http.Header {
 
 // Reset provides the differentiating feature from a regular ResponseWriter: it allows changing the
 // response completely even if some data has been written already. This behaviour cannot be guaranteed
-// if flush has been called explicitly so in that case it will panic.
+// if flush has been called explicitly so in that case it will panic. Headers marked with
+// [PreserveHeaders] are kept, also when they were marked on a buffer that this one writes into, such as
+// the buffer of a mux that mounted the handler.
 func (w *ResponseBuffer) Reset() {
 	if w.bodyFlushed {
 		panic("bhttp: response buffer is already flushed")
 	}
 
 	for k := range w.resp.Header() {
-		w.resp.Header().Del(k)
+		if !w.isPreserved(k) {
+			w.resp.Header().Del(k)
+		}
 	}
 
 	w.headerFlushed = false
@@ -104,6 +111,26 @@ func (w *ResponseBuffer) Reset() {
 	w.buf.Reset()
 }
 
+// PreserveHeaders marks header keys whose values survive a reset of the response writer, so that they are
+// also sent with the error response that [ToStd] renders after a handler failed. Middleware uses it for
+// headers that belong to every response, such as CORS headers or a request id. Headers must be set
+// before the handler writes to the response. It does nothing for writers that are not a [ResponseBuffer].
+func PreserveHeaders(w ResponseWriter, keys ...string) {
+	if p, ok := w.(interface{ preserveHeaders(keys ...string) }); ok {
+		p.preserveHeaders(keys...)
+	}
+}
+
+// pendingHeader returns the header that will be sent with the response, even after the handler wrote to
+// it, or nil if the header was already sent. Writers that are not a [ResponseBuffer] return their header.
+func pendingHeader(w ResponseWriter) http.Header {
+	if p, ok := w.(interface{ pendingHeader() http.Header }); ok {
+		return p.pendingHeader()
+	}
+
+	return w.Header()
+}
+
 // Write appends the contents of p to the buffered response, growing the internal buffer as needed. If
 // the write will cause the buffer be larger then the configure limit it will return ErrBufferFull.
 func (w *ResponseBuffer) Write(buf []byte) (int, error) {
@@ -125,7 +152,10 @@ func (w *ResponseBuffer) Write(buf []byte) (int, error) {
 // separately from FlushError to allow for emulating the original ResponseWriter behaviour more correctly.
 func (w *ResponseBuffer) FlushBuffer() error {
 	w.markHeaderAsFlushed()
-	w.resp.WriteHeader(w.status)
+
+	if !w.bodyFlushed {
+		w.resp.WriteHeader(w.status) // only once, the status is sent with the first flush.
+	}
 
 	_, err := w.buf.WriteTo(w.resp)
 	if err != nil {
@@ -152,12 +182,74 @@ func (w *ResponseBuffer) FlushError() error {
 	return nil
 }
 
+// Status returns the status code of the response, [http.StatusOK] unless set with WriteHeader.
+func (w *ResponseBuffer) Status() int {
+	return w.status
+}
+
+// BufferedHeader returns the headers that will be sent with the response. Unlike Header, it also
+// returns them once they can no longer be changed. The result must not be modified.
+func (w *ResponseBuffer) BufferedHeader() http.Header {
+	return w.resp.Header()
+}
+
+// BufferedBody returns the part of the body that is buffered and not yet flushed. The result is only
+// valid until the next write, reset or flush and must not be modified.
+func (w *ResponseBuffer) BufferedBody() []byte {
+	return w.buf.Bytes()
+}
+
 // Unwrap returns the underlying response writer. This is expected by the http.ResponseController to
 // allow it to call appropriate optional interface implementations.
 func (w *ResponseBuffer) Unwrap() http.ResponseWriter {
 	return w.resp
 }
 
+// preserveHeaders marks the header keys that survive a reset.
+func (w *ResponseBuffer) preserveHeaders(keys ...string) {
+	for _, key := range keys {
+		if key = http.CanonicalHeaderKey(key); !slices.Contains(w.preserved, key) {
+			w.preserved = append(w.preserved, key)
+		}
+	}
+}
+
+// isPreserved reports whether the header key was marked with [PreserveHeaders] on this buffer, or on any
+// buffer that it writes into.
+func (w *ResponseBuffer) isPreserved(key string) bool {
+	var next http.ResponseWriter = w
+	for next != nil {
+		switch rw := next.(type) {
+		case *ResponseBuffer:
+			if slices.Contains(rw.preserved, key) {
+				return true
+			}
+
+			next = rw.resp
+		case interface{ Unwrap() http.ResponseWriter }:
+			next = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// pendingHeader returns the header of the underlying writer until the response is flushed.
+func (w *ResponseBuffer) pendingHeader() http.Header {
+	if w.bodyFlushed {
+		return nil
+	}
+
+	return w.resp.Header()
+}
+
+// flushed reports whether any part of the response has been sent to the underlying writer.
+func (w *ResponseBuffer) flushed() bool {
+	return w.bodyFlushed
+}
+
 // markHeaderAsFlushed will mark the headers are being flushed to emulate the stdlib response writer
 // behaviour.
 func (w *ResponseBuffer) markHeaderAsFlushed() {
@@ -390,3 +390,52 @@ type failingResponseWriter struct {
 func (f failingResponseWriter) Write(p []byte) (int, error) {
 	return 0, errors.New("write fail")
 }
+
+// countingWriter counts the calls to WriteHeader on the underlying writer.
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	numWriteHeader int
+}
+
+func (w *countingWriter) WriteHeader(code int) {
+	w.numWriteHeader++
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func TestRepeatedFlushes(t *testing.T) {
+	rec := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	resp := newBufferResponse(rec, -1)
+	defer resp.Free()
+
+	resp.WriteHeader(http.StatusAccepted)
+
+	for _, chunk := range []string{"a", "b", "c"} {
+		_, err := resp.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.NoError(t, resp.FlushError())
+	}
+
+	require.NoError(t, resp.FlushBuffer())
+	require.Equal(t, 1, rec.numWriteHeader)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Equal(t, "abc", rec.Body.String())
+	require.True(t, resp.flushed())
+}
+
+func TestPreserveHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	resp := newBufferResponse(rec, -1)
+	defer resp.Free()
+
+	resp.Header().Set("Access-Control-Allow-Origin", "https://example.com")
+	resp.Header().Set("X-Other", "dropped")
+	PreserveHeaders(resp, "access-control-allow-origin", "Access-Control-Allow-Origin")
+
+	_, err := resp.Write([]byte("partial"))
+	require.NoError(t, err)
+
+	resp.Reset()
+	require.Equal(t, "https://example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, resp.Header().Get("X-Other"))
+	require.Equal(t, []string{"Access-Control-Allow-Origin"}, resp.preserved)
+}
@@ -1,19 +1,43 @@
 package bhttp
 
 import (
+	"net/url"
+	"strings"
+
 	"github.com/advdv/bhttp/internal/httppattern"
 	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
 )
 
-// Reverser keeps track of named patterns and  allows building URLS.
+// Params holds values for the wildcards of a pattern, by wildcard name.
+type Params map[string]string
+
+// Reverser keeps track of named patterns and  allows building URLS. Wildcard values are escaped such that
+// the handler observes the original value through [net/http.Request.PathValue].
 type Reverser struct {
-	pats map[string]*httppattern.Pattern
+	pats    map[string]*httppattern.Pattern
+	builder httppattern.Builder
+}
+
+// ReverserOption configures a [Reverser].
+type ReverserOption func(*Reverser)
+
+// RejectSlashes causes reversing to fail when a value for a single-segment wildcard contains a '/', instead
+// of escaping it. Use it to catch ids that were never meant to contain a slash.
+func RejectSlashes() ReverserOption {
+	return func(r *Reverser) {
+		r.builder.RejectSlash = true
+	}
 }
 
 // NewReverser inits the reverser.
-func NewReverser() *Reverser {
-	return &Reverser{make(map[string]*httppattern.Pattern)}
+func NewReverser(opts ...ReverserOption) *Reverser {
+	r := &Reverser{pats: make(map[string]*httppattern.Pattern)}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 // Reverse reverses the named pattern into a url.
@@ -23,7 +47,7 @@ func (r Reverser) Reverse(name string, vals ...string) (string, error) {
 		return "", errors.Newf("no pattern named: %q, got: %v", name, lo.Keys(r.pats))
 	}
 
-	res, err := httppattern.Build(pat, vals...)
+	res, err := r.builder.Build(pat, vals...)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to build")
 	}
@@ -31,6 +55,50 @@ func (r Reverser) Reverse(name string, vals ...string) (string, error) {
 	return res, nil
 }
 
+// ReverseWith reverses the named pattern into a url while taking wildcard values by name, so reordering the
+// wildcards of a pattern does not break the url. Every wildcard must be given a value and every value must
+// belong to a wildcard. A non-empty 'query' is encoded as the query string of the url.
+func (r Reverser) ReverseWith(name string, params Params, query url.Values) (string, error) {
+	pat, ok := r.pats[name]
+	if !ok {
+		return "", errors.Newf("no pattern named: %q, got: %v", name, lo.Keys(r.pats))
+	}
+
+	res, err := r.builder.BuildNamed(pat, params)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to build")
+	}
+
+	if len(query) > 0 {
+		res += "?" + query.Encode()
+	}
+
+	return res, nil
+}
+
+// ReverseURL reverses the named pattern into an absolute url. The scheme, host and path of 'base' are
+// prepended to the reversed path; the path of 'base' typically holds a deployment prefix such as an API
+// Gateway stage and the prefix of any mount the route is served under. Patterns with a host use their own
+// host, and only the scheme of 'base', since the path of 'base' is a prefix for its own host. Use [BaseURL]
+// to derive the base from an incoming request.
+func (r Reverser) ReverseURL(base *url.URL, name string, vals ...string) (string, error) {
+	if base == nil || base.Scheme == "" || base.Host == "" {
+		return "", errors.Newf("base url must have a scheme and host, got: %v", base)
+	}
+
+	res, err := r.Reverse(name, vals...)
+	if err != nil {
+		return "", err
+	}
+
+	// the reversed url starts with the host of the pattern, if it has one.
+	if r.pats[name].Host() != "" {
+		return base.Scheme + "://" + res, nil
+	}
+
+	return base.Scheme + "://" + base.Host + strings.TrimSuffix(base.EscapedPath(), "/") + res, nil
+}
+
 // Named is a convenience method that panics if naming the pattern fails.
 func (r Reverser) Named(name, str string) string {
 	str, err := r.NamedPattern(name, str)
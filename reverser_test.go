@@ -1,6 +1,7 @@
 package bhttp_test
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/advdv/bhttp"
@@ -49,4 +50,80 @@ func TestReverser(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "not enough values")
 	})
+
+	t.Run("should reverse absolute urls", func(t *testing.T) {
+		rev.Named("item", "GET /items/{id}")
+		rev.Named("hosted", "GET api.example.com/items/{id}")
+
+		for _, test := range []struct {
+			base string
+			name string
+			out  string
+		}{
+			{"https://example.com", "item", "https://example.com/items/1"},
+			{"https://example.com/", "item", "https://example.com/items/1"},
+			{"https://example.com/prod", "item", "https://example.com/prod/items/1"},
+			{"https://example.com/prod/api/", "item", "https://example.com/prod/api/items/1"},
+			{"http://localhost:8080", "hosted", "http://api.example.com/items/1"},
+			{"https://example.com/prod", "hosted", "https://api.example.com/items/1"},
+		} {
+			base, err := url.Parse(test.base)
+			require.NoError(t, err)
+
+			res, err := rev.ReverseURL(base, test.name, "1")
+			require.NoError(t, err)
+			assert.Equal(t, test.out, res)
+		}
+	})
+
+	t.Run("should error on relative base url", func(t *testing.T) {
+		_, err := rev.ReverseURL(&url.URL{Path: "/prod"}, "item", "1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must have a scheme and host")
+	})
+
+	t.Run("should reverse with named params and query", func(t *testing.T) {
+		rev.Named("post_comment", "/blog/{post}/comments/{comment}")
+
+		res, err := rev.ReverseWith("post_comment", bhttp.Params{"comment": "2", "post": "1"}, url.Values{
+			"page": {"3"},
+			"q":    {"a b&c"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "/blog/1/comments/2?page=3&q=a+b%26c", res)
+
+		res, err = rev.ReverseWith("post_comment", bhttp.Params{"comment": "2", "post": "1"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "/blog/1/comments/2", res)
+	})
+
+	t.Run("should error on unknown or missing named params", func(t *testing.T) {
+		_, err := rev.ReverseWith("post_comment", bhttp.Params{"post": "1", "id": "2"}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no wildcard(s) named: ["id"]`)
+
+		_, err = rev.ReverseWith("post_comment", bhttp.Params{"post": "1"}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `missing value for wildcard "comment"`)
+
+		_, err = rev.ReverseWith("bogus", nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no pattern named: "bogus"`)
+	})
+}
+
+func TestReverserEscaping(t *testing.T) {
+	rev := bhttp.NewReverser()
+	rev.Named("item", "/items/{id}")
+
+	res, err := rev.Reverse("item", "a/b?c")
+	require.NoError(t, err)
+	assert.Equal(t, "/items/a%2Fb%3Fc", res)
+
+	strict := bhttp.NewReverser(bhttp.RejectSlashes())
+	strict.Named("item", "/items/{id}")
+
+	_, err = strict.Reverse("item", "a/b")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `value "a/b" contains a '/'`)
 }
@@ -0,0 +1,155 @@
+package bhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/advdv/bhttp/internal/httppattern"
+)
+
+// routeGroup dispatches between patterns that only differ in their wildcard names and constraints. The
+// standard library considers such patterns to be in conflict, so the group is registered once on the
+// underlying mux and tries its candidates in order: constrained candidates in registration order, followed
+// by the (at most one) unconstrained candidate. When no candidate matches, the request is not found.
+//
+// Only patterns of the same shape share a group. When the constraints of a group fail, the request does not
+// fall through to a less specific pattern of another shape, such as "/items/{rest...}" for a request that
+// "/items/{id:int}" rejected, because the standard library already selected the most specific shape.
+type routeGroup struct {
+	mux        *ServeMux
+	pat        *httppattern.Pattern // the pattern that is registered on the underlying mux.
+	wildcards  []string             // wildcard names of the registered pattern.
+	candidates []*routeCandidate
+}
+
+// routeCandidate is a single pattern in a route group.
+type routeCandidate struct {
+	pat     *httppattern.Pattern
+	handler http.Handler
+}
+
+// register adds the handler for 'pat' to the route group with the same shape, creating it when necessary.
+func (m *ServeMux) register(pat *httppattern.Pattern, handler http.Handler) {
+	key := shapeOf(pat)
+
+	group, exists := m.groups[key]
+	if !exists {
+		group = &routeGroup{mux: m, pat: pat, wildcards: pat.Wildcards()}
+		m.mux.Handle(pat.StdString(), group)
+
+		if m.groups == nil {
+			m.groups = make(map[string]*routeGroup)
+		}
+
+		m.groups[key] = group
+	}
+
+	group.add(&routeCandidate{pat: pat, handler: handler})
+}
+
+func (g *routeGroup) add(c *routeCandidate) {
+	if c.pat.HasConstraints() {
+		idx := len(g.candidates)
+		if idx > 0 && !g.candidates[idx-1].pat.HasConstraints() {
+			idx-- // keep the unconstrained fallback last.
+		}
+
+		g.candidates = slices.Insert(g.candidates, idx, c)
+
+		return
+	}
+
+	for _, other := range g.candidates {
+		if !other.pat.HasConstraints() {
+			panic(fmt.Sprintf("bhttp: pattern %q conflicts with pattern %q: both match the same requests",
+				c.pat.String(), other.pat.String()))
+		}
+	}
+
+	g.candidates = append(g.candidates, c)
+}
+
+// ServeHTTP serves the request with the first candidate whose constraints are satisfied.
+func (g *routeGroup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, c := range g.candidates {
+		if r2, ok := c.match(r, g.wildcards); ok {
+			c.handler.ServeHTTP(w, r2)
+			return
+		}
+	}
+
+	g.mux.serveError(w, r, notFoundError)
+}
+
+// matches reports whether a candidate accepts the request, for a request that was not routed by the
+// underlying mux and so carries no path values, such as a probe for the allowed methods.
+func (g *routeGroup) matches(r *http.Request) bool {
+	probe := new(http.Request)
+	*probe = *r
+
+	for i, val := range g.pat.Values(r.URL.EscapedPath()) {
+		probe.SetPathValue(g.wildcards[i], val)
+	}
+
+	for _, c := range g.candidates {
+		if _, ok := c.match(probe, g.wildcards); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match checks the constraints of the candidate and returns the request as the candidate's handler should
+// observe it: with the path values under the candidate's own wildcard names and its own pattern.
+func (c *routeCandidate) match(r *http.Request, wildcards []string) (*http.Request, bool) {
+	names := c.pat.Wildcards()
+
+	vals := make([]string, len(wildcards))
+	for i, wildcard := range wildcards {
+		vals[i] = r.PathValue(wildcard)
+
+		if con := c.pat.Constraint(names[i]); con != nil && !con.Match(vals[i]) {
+			return nil, false
+		}
+	}
+
+	if r.Pattern == c.pat.String() {
+		return r, true
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Pattern = c.pat.String()
+
+	for i, name := range names {
+		r2.SetPathValue(name, vals[i])
+	}
+
+	return r2, true
+}
+
+// shapeOf returns a key that is equal for patterns that only differ in wildcard names and constraints.
+func shapeOf(pat *httppattern.Pattern) string {
+	var b strings.Builder
+
+	b.WriteString(pat.Method() + " " + pat.Host())
+
+	for _, seg := range pat.Segments() {
+		b.WriteByte('/')
+
+		switch {
+		case seg.Multi:
+			b.WriteString("{...}")
+		case seg.Wild:
+			b.WriteString("{}")
+		default:
+			b.WriteString(url.PathEscape(seg.Value))
+		}
+	}
+
+	return b.String()
+}
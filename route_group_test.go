@@ -0,0 +1,125 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstrainedRoutes(t *testing.T) {
+	mux := bhttp.NewServeMux()
+
+	reply := func(kind, wildcard string) bhttp.HandlerFunc {
+		return func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+			_, err := fmt.Fprintf(w, "%s:%s:%s", kind, r.PathValue(wildcard), r.Pattern)
+			return err
+		}
+	}
+
+	mux.HandleFunc("GET /posts/{slug}", reply("slug", "slug"))
+	mux.HandleFunc("GET /posts/{id:int}", reply("id", "id"), bhttp.Name("post-by-id"))
+	mux.HandleFunc("GET /files/{name:[a-z]+}", reply("file", "name"))
+
+	for _, test := range []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/posts/123", http.StatusOK, "id:123:GET /posts/{id:int}"},
+		{"/posts/hello", http.StatusOK, "slug:hello:GET /posts/{slug}"},
+		{"/files/readme", http.StatusOK, "file:readme:GET /files/{name:[a-z]+}"},
+		{"/files/README", http.StatusNotFound, "Not Found: no route matches the request path\n"},
+	} {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, test.path, nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, test.wantCode, rec.Code, test.path)
+		require.Equal(t, test.wantBody, rec.Body.String(), test.path)
+	}
+
+	loc, err := mux.Reverse("post-by-id", "123")
+	require.NoError(t, err)
+	require.Equal(t, "/posts/123", loc)
+
+	_, err = mux.Reverse("post-by-id", "hello")
+	require.ErrorContains(t, err, `does not satisfy constraint "int"`)
+
+	var constraints []string
+	for _, route := range mux.Routes() {
+		constraints = append(constraints, route.Segments[1].Constraint)
+	}
+
+	require.Equal(t, []string{"", "int", "[a-z]+"}, constraints)
+}
+
+func TestConstrainedRoutesMethodNotAllowed(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.HandleFunc("GET /items/{id:int}", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil })
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/items/abc", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/items/42", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+func TestConstrainedRoutesNoFallthroughBetweenShapes(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.HandleFunc("GET /items/{id:int}", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil })
+	mux.HandleFunc("GET /items/{rest...}", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		_, err := fmt.Fprint(w, "rest")
+		return err
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/a/b", nil))
+	require.Equal(t, "rest", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/abc", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code, "the more specific shape was selected, its constraint failed")
+}
+
+func TestConstrainedRoutesRenameWildcards(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.HandleFunc("GET /{a}/{b:int}", func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		_, err := fmt.Fprintf(w, "a=%s b=%s", r.PathValue("a"), r.PathValue("b"))
+		return err
+	})
+	mux.HandleFunc("GET /{b:int}/{a}", func(_ context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		_, err := fmt.Fprintf(w, "b=%s a=%s", r.PathValue("b"), r.PathValue("a"))
+		return err
+	})
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/1/x", nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "b=1 a=x", rec.Body.String())
+
+	rec, req = httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x/2", nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "a=x b=2", rec.Body.String())
+}
+
+func TestConstrainedRoutesConflict(t *testing.T) {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMux()
+	mux.HandleFunc("GET /posts/{id:int}", noop)
+	mux.HandleFunc("GET /posts/{slug}", noop)
+
+	require.PanicsWithValue(t,
+		`bhttp: pattern "GET /posts/{name}" conflicts with pattern "GET /posts/{slug}": both match the same requests`,
+		func() { mux.HandleFunc("GET /posts/{name}", noop) })
+
+	require.PanicsWithValue(t, "bhttp: failed to parse pattern: invalid constraint \"(\": "+
+		"error parsing regexp: missing closing ): `^(?:()$`",
+		func() { mux.HandleFunc("GET /x/{name:(}", noop) })
+}
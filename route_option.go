@@ -0,0 +1,26 @@
+package bhttp
+
+// RouteOption configures a route when it is registered on a [ServeMux].
+type RouteOption func(*routeConfig)
+
+// routeConfig holds the settings of a route that are collected from its options.
+type routeConfig struct {
+	name   string
+	policy Policy
+	meta   map[any]any // values by *MetaKey.
+}
+
+// Name names the route, so that its URL can be reversed and middleware can refer to it.
+func Name(name string) RouteOption {
+	return func(c *routeConfig) { c.name = name }
+}
+
+// newRouteConfig applies the options in order, later options override earlier ones.
+func newRouteConfig(opts ...RouteOption) routeConfig {
+	var cfg routeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
@@ -0,0 +1,85 @@
+package bhttp
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/advdv/bhttp/internal/httppattern"
+)
+
+// RouteInfo describes a pattern that is registered on a [ServeMux].
+type RouteInfo struct {
+	Name        string    // name given at registration, empty for unnamed routes.
+	Pattern     string    // pattern string as registered on the underlying mux.
+	Method      string    // method of the pattern, empty if it matches any method.
+	Host        string    // host of the pattern, empty if it matches any host.
+	Path        string    // path of the pattern, without method and host.
+	Segments    []Segment // parsed path segments.
+	Wildcards   []string  // names of the path wildcards, in order.
+	Mounted     bool      // whether the pattern was registered through one of the Mount methods.
+	MountPrefix string    // path prefix that is stripped for mounted patterns, empty otherwise.
+	BufferLimit int       // response buffer limit in bytes, -1 if unlimited.
+	Policy      Policy    // who may call the route, as declared at registration.
+
+	meta map[any]any // metadata values by *MetaKey, read through MetaKey.Of.
+}
+
+// Segment describes a single path segment of a route pattern.
+type Segment struct {
+	Value      string // literal text, wildcard name or "/" for a trailing "{$}".
+	Wild       bool   // whether the segment is a wildcard.
+	Multi      bool   // whether the segment is a "..." wildcard that matches the rest of the path.
+	Constraint string // constraint of the wildcard, e.g. "int" for "{id:int}", empty if unconstrained.
+}
+
+// Routes returns a description of every pattern registered on the mux, in registration order. Mounting
+// registers two patterns: the exact prefix and its subtree. The returned values are copies and can be
+// modified freely.
+func (m *ServeMux) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(m.routes))
+	for _, rt := range m.routes {
+		routes = append(routes, rt.clone())
+	}
+
+	return routes
+}
+
+// newRouteInfo describes the parsed pattern 'pat' that is registered with the route config 'cfg'.
+func newRouteInfo(pat *httppattern.Pattern, cfg routeConfig, mountPrefix string, bufLimit int) *RouteInfo {
+	segs := pat.Segments()
+	info := &RouteInfo{
+		Name:        cfg.name,
+		Pattern:     pat.String(),
+		Method:      pat.Method(),
+		Host:        pat.Host(),
+		Path:        pat.Path(),
+		Segments:    make([]Segment, 0, len(segs)),
+		Wildcards:   pat.Wildcards(),
+		Mounted:     mountPrefix != "",
+		MountPrefix: mountPrefix,
+		BufferLimit: bufLimit,
+		Policy:      cfg.policy.clone(),
+		meta:        cfg.meta,
+	}
+
+	for _, seg := range segs {
+		exp := Segment{Value: seg.Value, Wild: seg.Wild, Multi: seg.Multi}
+		if seg.Constraint != nil {
+			exp.Constraint = seg.Constraint.String()
+		}
+
+		info.Segments = append(info.Segments, exp)
+	}
+
+	return info
+}
+
+func (ri *RouteInfo) clone() RouteInfo {
+	c := *ri
+	c.Segments = slices.Clone(ri.Segments)
+	c.Wildcards = slices.Clone(ri.Wildcards)
+	c.Policy = ri.Policy.clone()
+	c.meta = maps.Clone(ri.meta)
+
+	return c
+}
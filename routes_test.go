@@ -0,0 +1,131 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+
+	mux := bhttp.NewServeMuxWith(1024, bhttp.NewTestLogger(t), http.NewServeMux(), bhttp.NewReverser())
+	mux.HandleFunc("GET /items/{id}", noop, bhttp.Name("get-item"))
+	mux.HandleFunc("example.com/files/{path...}", noop)
+	mux.HandleFunc("/{$}", noop)
+	mux.MountFunc("POST /api", noop)
+
+	routes := mux.Routes()
+	require.Equal(t, []bhttp.RouteInfo{
+		{
+			Name:    "get-item",
+			Pattern: "GET /items/{id}",
+			Path:    "/items/{id}",
+			Method:  http.MethodGet,
+			Segments: []bhttp.Segment{
+				{Value: "items"},
+				{Value: "id", Wild: true},
+			},
+			Wildcards:   []string{"id"},
+			BufferLimit: 1024,
+		},
+		{
+			Pattern: "example.com/files/{path...}",
+			Host:    "example.com",
+			Path:    "/files/{path...}",
+			Segments: []bhttp.Segment{
+				{Value: "files"},
+				{Value: "path", Wild: true, Multi: true},
+			},
+			Wildcards:   []string{"path"},
+			BufferLimit: 1024,
+		},
+		{
+			Pattern:     "/{$}",
+			Path:        "/{$}",
+			Segments:    []bhttp.Segment{{Value: "/"}},
+			BufferLimit: 1024,
+		},
+		{
+			Pattern:     "POST /api",
+			Path:        "/api",
+			Method:      http.MethodPost,
+			Segments:    []bhttp.Segment{{Value: "api"}},
+			Mounted:     true,
+			MountPrefix: "/api",
+			BufferLimit: 1024,
+		},
+		{
+			Pattern:     "POST /api/",
+			Path:        "/api/",
+			Method:      http.MethodPost,
+			Segments:    []bhttp.Segment{{Value: "api"}, {Wild: true, Multi: true}},
+			Mounted:     true,
+			MountPrefix: "/api",
+			BufferLimit: 1024,
+		},
+	}, routes)
+
+	t.Run("returns copies", func(t *testing.T) {
+		routes[0].Segments[0].Value = "changed"
+		routes[0].Wildcards[0] = "changed"
+		require.Equal(t, "items", mux.Routes()[0].Segments[0].Value)
+		require.Equal(t, "id", mux.Routes()[0].Wildcards[0])
+	})
+}
+
+func TestMatchedRoute(t *testing.T) {
+	var mwRoute *bhttp.RouteInfo
+
+	mux := bhttp.NewServeMux()
+	mux.Use(func(next bhttp.BareHandler) bhttp.BareHandler {
+		return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+			mwRoute = bhttp.MatchedRoute(r.Context())
+			return next.ServeBareBHTTP(w, r)
+		})
+	})
+
+	mux.HandleFunc("GET /items/{id}", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		route := bhttp.MatchedRoute(ctx)
+		fmt.Fprintf(w, "%s|%s|%q", route.Name, route.Pattern, bhttp.MountPrefix(ctx))
+		return nil
+	}, bhttp.Name("get-item"))
+
+	inner := bhttp.NewServeMux()
+	inner.HandleFunc("GET /users/{id}", func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request) error {
+		route := bhttp.MatchedRoute(ctx)
+		fmt.Fprintf(w, "%s|%s|%s|%s", route.Name, route.Pattern, bhttp.MountPrefix(ctx), r.URL.Path)
+		return nil
+	}, bhttp.Name("get-user"))
+	mux.MountStd("/api", inner)
+
+	t.Run("direct route", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, `get-item|GET /items/{id}|""`, rec.Body.String())
+		require.Equal(t, "get-item", mwRoute.Name)
+	})
+
+	t.Run("nested mux", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/2", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, `get-user|GET /users/{id}|/api|/users/2`, rec.Body.String())
+		require.Equal(t, "/api/", mwRoute.Pattern)
+		require.Equal(t, "/api", mwRoute.MountPrefix)
+	})
+
+	t.Run("unmatched", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bogus", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Nil(t, mwRoute)
+	})
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/advdv/bhttp/internal/httppattern"
 )
 
 // ServeMux is an HTTP multiplexer with buffered responses, error handling, and named routes.
@@ -12,6 +16,9 @@ type ServeMux struct {
 	bufLimit    int
 	reverser    *Reverser
 	mux         *http.ServeMux
+	routes      []*RouteInfo
+	handlers    map[*RouteInfo]Handler // handlers as registered, to describe their operations.
+	groups      map[string]*routeGroup
 	middlewares struct {
 		captured bool
 		buffered []Middleware
@@ -38,6 +45,18 @@ func (m *ServeMux) Reverse(name string, vals ...string) (string, error) {
 	return m.reverser.Reverse(name, vals...)
 }
 
+// ReverseWith returns the url based on the name, parameter values by wildcard name and query. See
+// [Reverser.ReverseWith] for details.
+func (m *ServeMux) ReverseWith(name string, params Params, query url.Values) (string, error) {
+	return m.reverser.ReverseWith(name, params, query)
+}
+
+// ReverseURL returns the absolute url based on the base url, name and parameter values. See
+// [Reverser.ReverseURL] for details.
+func (m *ServeMux) ReverseURL(base *url.URL, name string, vals ...string) (string, error) {
+	return m.reverser.ReverseURL(base, name, vals...)
+}
+
 // Use allows providing of middleware.
 func (m *ServeMux) Use(mw ...Middleware) {
 	m.ensureNoUseAfterHandle()
@@ -45,42 +64,78 @@ func (m *ServeMux) Use(mw ...Middleware) {
 }
 
 // HandleFunc handles the request given the pattern using a function.
-func (m *ServeMux) HandleFunc(pattern string, handler HandlerFunc, name ...string) {
-	m.Handle(pattern, handler, name...)
+func (m *ServeMux) HandleFunc(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	m.Handle(pattern, handler, opts...)
 }
 
 // HandleStd registers a standard library [http.Handler] for the given pattern. Middleware
 // registered via [ServeMux.Use] is applied. See the package-level section
 // "Standard library handlers and error ownership" for details on error handling behavior.
-func (m *ServeMux) HandleStd(pattern string, handler http.Handler, name ...string) {
+func (m *ServeMux) HandleStd(pattern string, handler http.Handler, opts ...RouteOption) {
 	m.Handle(pattern, HandlerFunc(func(_ context.Context, w ResponseWriter, r *http.Request) error {
 		handler.ServeHTTP(w, r)
 		return nil
-	}), name...)
+	}), opts...)
 }
 
-// Handle handles the request given a handler.
-func (m *ServeMux) Handle(pattern string, handler Handler, name ...string) {
-	m.handle(pattern, ToStd(
-		Wrap(handler, m.middlewares.buffered...),
-		m.bufLimit,
-		m.logs,
-	), name...)
+// Handle handles the request given a handler. Options name the route and declare its [Policy], which is
+// enforced after all middleware registered with [ServeMux.Use] has run:
+//
+//	mux.Handle("DELETE /items/{id}", h, bhttp.Name("delete-item"), bhttp.Require("items:write"))
+func (m *ServeMux) Handle(pattern string, handler Handler, opts ...RouteOption) {
+	cfg := newRouteConfig(opts...)
+	info := m.handle(pattern, Wrap(handler, m.routeMiddleware(pattern, cfg)...), "", cfg)
+
+	if m.handlers == nil {
+		m.handlers = make(map[*RouteInfo]Handler)
+	}
+
+	m.handlers[info] = handler
 }
 
-// ServeHTTP makes the server mux implement the http.Handler interface.
+// ServeHTTP makes the server mux implement the http.Handler interface. Requests that match no pattern
+// are answered with a [CodeNotFound] or [CodeMethodNotAllowed] error that passes through the middleware
+// registered with [ServeMux.Use].
 func (m *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.isUnmatched(r) {
+		m.serveUnmatched(w, r)
+		return
+	}
+
 	m.mux.ServeHTTP(w, r)
 }
 
-func (m *ServeMux) handle(pattern string, handler http.Handler, name ...string) {
+func (m *ServeMux) handle(pattern string, handler BareHandler, mountPrefix string, cfg routeConfig) *RouteInfo {
 	m.middlewares.captured = true
 
-	if len(name) > 0 {
-		pattern = m.reverser.Named(name[0], pattern)
+	if cfg.name != "" {
+		pattern = m.reverser.Named(cfg.name, pattern)
+	}
+
+	pat, err := httppattern.ParsePattern(pattern)
+	if err != nil {
+		panic("bhttp: failed to parse pattern: " + err.Error())
+	}
+
+	info := newRouteInfo(pat, cfg, mountPrefix, m.bufLimit)
+	m.register(pat, ToStd(withRouteInfo(info, handler), m.bufLimit, m.logs))
+	m.routes = append(m.routes, info)
+
+	return info
+}
+
+// routeMiddleware returns the middleware registered with [ServeMux.Use], followed by the enforcement of
+// the route's policy when it is restricted.
+func (m *ServeMux) routeMiddleware(pattern string, cfg routeConfig) []Middleware {
+	if cfg.policy.Public && cfg.policy.Restricted() {
+		panic("bhttp: route " + pattern + " cannot be both public and restricted")
+	}
+
+	if !cfg.policy.Restricted() {
+		return m.middlewares.buffered
 	}
 
-	m.mux.Handle(pattern, handler)
+	return append(slices.Clone(m.middlewares.buffered), enforcePolicy(cfg.policy))
 }
 
 func (m *ServeMux) ensureNoUseAfterHandle() {
@@ -25,7 +25,7 @@ func middleware1(next bhttp.BareHandler) bhttp.BareHandler {
 func TestServeMux(t *testing.T) {
 	mux := bhttp.NewServeMux()
 	mux.Use(middleware1)
-	mux.HandleFunc("GET /blog/{slug}", serveBlogPost, "blog_post")
+	mux.HandleFunc("GET /blog/{slug}", serveBlogPost, bhttp.Name("blog_post"))
 
 	loc, err := mux.Reverse("blog_post", "foo")
 	require.NoError(t, err)
@@ -86,7 +86,7 @@ func TestHandleStdNamed(t *testing.T) {
 	mux := bhttp.NewServeMux()
 	mux.HandleStd("GET /metrics", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		fmt.Fprint(w, "metrics")
-	}), "metrics")
+	}), bhttp.Name("metrics"))
 
 	loc, err := mux.Reverse("metrics")
 	require.NoError(t, err)
@@ -101,7 +101,7 @@ func TestHandleStdNamed(t *testing.T) {
 
 func TestUseAfterHandle(t *testing.T) {
 	mux := bhttp.NewServeMux()
-	mux.HandleFunc("GET /blog/{slug}", serveBlogPost, "blog_post")
+	mux.HandleFunc("GET /blog/{slug}", serveBlogPost, bhttp.Name("blog_post"))
 	require.PanicsWithValue(t, "bhttp: cannot call Use() after calling Handle", func() {
 		mux.Use(middleware1)
 	})
@@ -0,0 +1,417 @@
+package bhttp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultSessionMaxAge is how long a session lasts after it was last saved.
+const DefaultSessionMaxAge = 14 * 24 * time.Hour
+
+// DefaultSessionCookie is the name of the session cookie.
+const DefaultSessionCookie = "session"
+
+// maxCookieSize is the size limit of a cookie value that browsers reliably accept.
+const maxCookieSize = 4096
+
+// ErrNoSession is returned by [SessionFrom] when the request was not handled by the [Sessions] middleware.
+var ErrNoSession = errors.New("no session middleware")
+
+// Session holds the data of a client across requests. Values are stored as JSON. A session is only saved
+// when it was modified, and is not safe for concurrent use.
+type Session struct {
+	values   map[string]json.RawMessage
+	isNew    bool
+	modified bool
+	renew    bool
+	destroy  bool
+}
+
+// IsNew reports whether the client did not have a session yet.
+func (s *Session) IsNew() bool { return s.isNew }
+
+// Get decodes the value stored under 'key' into 'dst', and reports whether there was one.
+func (s *Session) Get(key string, dst any) (bool, error) {
+	raw, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return true, errors.Wrapf(err, "failed to decode session value %q", key)
+	}
+
+	return true, nil
+}
+
+// Set stores the value under 'key'.
+func (s *Session) Set(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode session value %q", key)
+	}
+
+	s.values[key] = raw
+	s.modified, s.destroy = true, false
+
+	return nil
+}
+
+// Delete removes the value stored under 'key'.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Renew moves the session to a new id while keeping its values. Call it when the privileges of the
+// client change, such as after logging in, to prevent session fixation.
+func (s *Session) Renew() {
+	s.renew, s.modified = true, true
+}
+
+// Destroy removes all values and the session itself, and expires the cookie.
+func (s *Session) Destroy() {
+	clear(s.values)
+	s.destroy, s.modified = true, true
+}
+
+// SessionOption configures [Sessions].
+type SessionOption func(*sessionOptions)
+
+type sessionOptions struct {
+	cookie  http.Cookie
+	maxAge  time.Duration
+	encrypt bool
+}
+
+// WithSessionCookie sets the template of the session cookie, its value and expiry are ignored. It
+// defaults to a secure, HTTP-only, "SameSite=Lax" cookie named [DefaultSessionCookie] with path "/".
+func WithSessionCookie(cookie http.Cookie) SessionOption {
+	return func(o *sessionOptions) { o.cookie = cookie }
+}
+
+// WithSessionMaxAge sets how long a session lasts after it was last saved, it defaults to
+// [DefaultSessionMaxAge].
+func WithSessionMaxAge(d time.Duration) SessionOption {
+	return func(o *sessionOptions) { o.maxAge = d }
+}
+
+// WithSessionEncryption encrypts the cookie, instead of only signing it, so that clients cannot read it.
+// This matters for the [CookieSessionStore], which keeps all session data in the cookie.
+func WithSessionEncryption() SessionOption {
+	return func(o *sessionOptions) { o.encrypt = true }
+}
+
+// Sessions returns middleware that makes a [Session] available to handlers through [SessionFrom]. The
+// session is loaded when it is first asked for. It is saved when it was modified and the handler
+// succeeded, just before the response is flushed, together with the cookie. When the handler fails, its
+// changes are discarded along with the rest of the buffered response. Register it before middleware
+// that may fail the request, so that its failures also discard the session changes.
+//
+// The cookie is signed with the first of 'keys', and optionally encrypted. Cookies signed with the other
+// keys remain valid, so keys can be rotated by adding a new key in front. Keys must be at least 32
+// random bytes. Sessions panics when there are no valid keys.
+func Sessions(store SessionStore, keys [][]byte, opts ...SessionOption) Middleware {
+	o := sessionOptions{
+		cookie: http.Cookie{
+			Name: DefaultSessionCookie, Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode,
+		},
+		maxAge: DefaultSessionMaxAge,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	codec := newSessionCodec(o.cookie.Name, keys, o.encrypt)
+
+	return func(next BareHandler) BareHandler {
+		return BareHandlerFunc(func(w ResponseWriter, r *http.Request) error {
+			state := &sessionState{store: store, codec: codec, maxAge: o.maxAge, req: r}
+			if err := next.ServeBareBHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeySession, state))); err != nil {
+				return err
+			}
+
+			if state.session == nil || !state.session.modified {
+				return nil
+			}
+
+			return state.commit(r.Context(), w, o)
+		})
+	}
+}
+
+// SessionFrom returns the session of the request, loading it from the store on first use.
+func SessionFrom(ctx context.Context) (*Session, error) {
+	state, _ := ctx.Value(ctxKeySession).(*sessionState)
+	if state == nil {
+		return nil, ErrNoSession
+	}
+
+	return state.load(ctx)
+}
+
+// sessionState tracks the session of a single request.
+type sessionState struct {
+	store   SessionStore
+	codec   *sessionCodec
+	maxAge  time.Duration
+	req     *http.Request
+	token   string // the verified store token from the cookie, empty for new sessions.
+	session *Session
+	err     error
+}
+
+// load decodes the cookie and loads the session from the store, once. Missing, invalid or expired
+// cookies and sessions result in a new session.
+func (st *sessionState) load(ctx context.Context) (*Session, error) {
+	if st.session != nil || st.err != nil {
+		return st.session, st.err
+	}
+
+	st.session = &Session{values: map[string]json.RawMessage{}, isNew: true}
+
+	cookie, err := st.req.Cookie(st.codec.name)
+	if err != nil {
+		return st.session, nil
+	}
+
+	token, ok := st.codec.decode(cookie.Value, st.maxAge)
+	if !ok {
+		return st.session, nil
+	}
+
+	data, err := st.store.Load(ctx, token)
+	if err != nil {
+		st.session, st.err = nil, errors.Wrap(err, "failed to load session")
+		return nil, st.err
+	}
+
+	if data == nil {
+		return st.session, nil
+	}
+
+	if err := json.Unmarshal(data, &st.session.values); err != nil {
+		st.session.values = map[string]json.RawMessage{} // treat corrupt data as a new session.
+		return st.session, nil
+	}
+
+	st.token, st.session.isNew = token, false
+
+	return st.session, nil
+}
+
+// commit saves or removes the modified session and sets the cookie accordingly.
+func (st *sessionState) commit(ctx context.Context, w ResponseWriter, o sessionOptions) error {
+	hdr := pendingHeader(w)
+	if hdr == nil {
+		return errors.New("session was modified after the response was flushed")
+	}
+
+	sess, cookie := st.session, o.cookie
+
+	if sess.destroy || sess.renew {
+		if st.token != "" {
+			if err := st.store.Delete(ctx, st.token); err != nil {
+				return errors.Wrap(err, "failed to delete session")
+			}
+		}
+
+		st.token = ""
+	}
+
+	if sess.destroy {
+		cookie.MaxAge = -1
+		hdr.Add("Set-Cookie", cookie.String())
+
+		return nil
+	}
+
+	data, err := json.Marshal(sess.values)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode session")
+	}
+
+	token, err := st.store.Save(ctx, st.token, data, st.maxAge)
+	if err != nil {
+		return errors.Wrap(err, "failed to save session")
+	}
+
+	cookie.Value = st.codec.encode(token)
+	cookie.MaxAge = int(st.maxAge / time.Second)
+
+	if len(cookie.Value) > maxCookieSize {
+		return errors.Newf("session cookie of %d bytes exceeds %d bytes", len(cookie.Value), maxCookieSize)
+	}
+
+	hdr.Add("Set-Cookie", cookie.String())
+
+	return nil
+}
+
+// sessionCSRFKey is the session value that holds the synchronizer token of [SessionCSRFStore].
+const sessionCSRFKey = "_csrf"
+
+// SessionCSRFStore returns a [CSRFStore] that keeps the synchronizer token of the [CSRF] middleware in
+// the session. The [Sessions] middleware must run before the CSRF middleware.
+func SessionCSRFStore() CSRFStore { return sessionCSRFStore{} }
+
+type sessionCSRFStore struct{}
+
+func (sessionCSRFStore) CSRFToken(r *http.Request) (string, error) {
+	sess, err := SessionFrom(r.Context())
+	if err != nil {
+		return "", err
+	}
+
+	var token string
+	if _, err := sess.Get(sessionCSRFKey, &token); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (sessionCSRFStore) SetCSRFToken(_ ResponseWriter, r *http.Request, token string) error {
+	sess, err := SessionFrom(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return sess.Set(sessionCSRFKey, token)
+}
+
+// sessionCodec signs, and optionally encrypts, the store token in the session cookie. The cookie name
+// is authenticated along with the value, so a value cannot be moved to another cookie.
+type sessionCodec struct {
+	name    string
+	keys    []sessionKey
+	encrypt bool
+}
+
+// sessionKey holds the keys that are derived from a configured key, so that it is not used for both
+// signing and encryption.
+type sessionKey struct {
+	sign []byte
+	aead cipher.AEAD
+}
+
+func newSessionCodec(name string, keys [][]byte, encrypt bool) *sessionCodec {
+	if len(keys) < 1 {
+		panic("bhttp: sessions require at least one key")
+	}
+
+	codec := &sessionCodec{name: name, encrypt: encrypt}
+
+	for _, key := range keys {
+		if len(key) < 32 {
+			panic("bhttp: session keys must be at least 32 bytes")
+		}
+
+		block, err := aes.NewCipher(deriveKey(key, "encrypt"))
+		if err != nil {
+			panic("bhttp: " + err.Error())
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic("bhttp: " + err.Error())
+		}
+
+		codec.keys = append(codec.keys, sessionKey{sign: deriveKey(key, "sign"), aead: aead})
+	}
+
+	return codec
+}
+
+// deriveKey derives a 32 byte key for the given purpose.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("bhttp session " + purpose))
+
+	return mac.Sum(nil)
+}
+
+// encode returns the cookie value for the token, which includes the time it was issued at.
+func (c *sessionCodec) encode(token string) string {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Unix())) //nolint:gosec
+	payload = append(payload, token...)
+	key := c.keys[0]
+
+	if c.encrypt {
+		nonce := make([]byte, key.aead.NonceSize())
+		_, _ = rand.Read(nonce)
+
+		return base64.RawURLEncoding.EncodeToString(key.aead.Seal(nonce, nonce, payload, []byte(c.name)))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(key, encoded))
+}
+
+// decode verifies the cookie value with any of the keys, and returns its token if it is not older than
+// 'maxAge'.
+func (c *sessionCodec) decode(value string, maxAge time.Duration) (string, bool) {
+	for _, key := range c.keys {
+		if payload, ok := c.open(key, value); ok && len(payload) >= 8 {
+			issued := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0) //nolint:gosec
+			if time.Since(issued) > maxAge {
+				return "", false
+			}
+
+			return string(payload[8:]), true
+		}
+	}
+
+	return "", false
+}
+
+// open returns the payload of a value that was encoded with 'key'.
+func (c *sessionCodec) open(key sessionKey, value string) ([]byte, bool) {
+	if c.encrypt {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(data) < key.aead.NonceSize() {
+			return nil, false
+		}
+
+		nonce, sealed := data[:key.aead.NonceSize()], data[key.aead.NonceSize():]
+		payload, err := key.aead.Open(nil, nonce, sealed, []byte(c.name))
+
+		return payload, err == nil
+	}
+
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.sign(key, encoded)) {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	return payload, err == nil
+}
+
+func (c *sessionCodec) sign(key sessionKey, encoded string) []byte {
+	mac := hmac.New(sha256.New, key.sign)
+	mac.Write([]byte(c.name + "|" + encoded))
+
+	return mac.Sum(nil)
+}
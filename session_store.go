@@ -0,0 +1,114 @@
+package bhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the data of sessions. The [Sessions] middleware stores the token that identifies a
+// session in a signed cookie: for server-side stores that is a random session id, the [CookieSessionStore]
+// uses the session data itself.
+type SessionStore interface {
+	// Load returns the data for the token, or nil if the session does not exist or has expired.
+	Load(ctx context.Context, token string) ([]byte, error)
+	// Save stores the data of the session with the token, which is empty for a new session, and returns
+	// the token to put in the cookie.
+	Save(ctx context.Context, token string, data []byte, ttl time.Duration) (string, error)
+	// Delete removes the session with the token.
+	Delete(ctx context.Context, token string) error
+}
+
+// CookieSessionStore keeps all session data in the cookie, so it needs no server-side state. Sessions are
+// limited to what fits in a cookie, and cannot be revoked before they expire. Combine it with
+// [WithSessionEncryption] to keep the data private.
+type CookieSessionStore struct{}
+
+// Load implements [SessionStore].
+func (CookieSessionStore) Load(_ context.Context, token string) ([]byte, error) {
+	return []byte(token), nil
+}
+
+// Save implements [SessionStore].
+func (CookieSessionStore) Save(_ context.Context, _ string, data []byte, _ time.Duration) (string, error) {
+	return string(data), nil
+}
+
+// Delete implements [SessionStore].
+func (CookieSessionStore) Delete(context.Context, string) error { return nil }
+
+// MemorySessionStore keeps sessions in memory. Sessions are lost on restart and are not shared between
+// instances, so it is meant for tests and single instance deployments.
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	nextSweep time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemorySessionStore inits an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load implements [SessionStore].
+func (s *MemorySessionStore) Load(_ context.Context, token string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[token]
+	if !ok || time.Now().After(sess.expires) {
+		return nil, nil
+	}
+
+	return sess.data, nil
+}
+
+// Save implements [SessionStore].
+func (s *MemorySessionStore) Save(_ context.Context, token string, data []byte, ttl time.Duration) (string, error) {
+	if token == "" {
+		token = NewSessionID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for id, sess := range s.sessions {
+			if now.After(sess.expires) {
+				delete(s.sessions, id)
+			}
+		}
+
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	s.sessions[token] = memorySession{data: data, expires: now.Add(ttl)}
+
+	return token, nil
+}
+
+// Delete implements [SessionStore].
+func (s *MemorySessionStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+
+	return nil
+}
+
+// NewSessionID returns a random session id, for use by server-side [SessionStore] implementations.
+func NewSessionID() string {
+	id := make([]byte, 32)
+	_, _ = rand.Read(id)
+
+	return base64.RawURLEncoding.EncodeToString(id)
+}
@@ -0,0 +1,275 @@
+package bhttp_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sessionKey1 = bytes.Repeat([]byte{1}, 32)
+	sessionKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+// sessionMux counts visits in the session, and supports logging in, out and failing halfway.
+func sessionMux(store bhttp.SessionStore, keys [][]byte, opts ...bhttp.SessionOption) *bhttp.ServeMux {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.Sessions(store, keys, opts...))
+	mux.HandleFunc("GET /visit", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		var visits int
+		if _, err := sess.Get("visits", &visits); err != nil {
+			return err
+		}
+
+		if err := sess.Set("visits", visits+1); err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%d %v", visits+1, sess.IsNew())
+
+		return err
+	})
+	mux.HandleFunc("GET /peek", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		var visits int
+		_, err = sess.Get("visits", &visits)
+		fmt.Fprintf(w, "%d", visits)
+
+		return err
+	})
+	mux.HandleFunc("POST /login", func(ctx context.Context, _ bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		sess.Renew()
+
+		return sess.Set("user", "u1")
+	})
+	mux.HandleFunc("POST /logout", func(ctx context.Context, _ bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		sess.Destroy()
+
+		return nil
+	})
+	mux.HandleFunc("GET /fail", func(ctx context.Context, _ bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := sess.Set("visits", 100); err != nil {
+			return err
+		}
+
+		return errors.New("boom")
+	})
+
+	return mux
+}
+
+// serveSession serves a request with the cookie and returns the response and the new session cookie.
+func serveSession(mux http.Handler, method, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	mux.ServeHTTP(rec, req)
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == bhttp.DefaultSessionCookie {
+			return rec, c
+		}
+	}
+
+	return rec, nil
+}
+
+func TestSessions(t *testing.T) {
+	for name, store := range map[string]bhttp.SessionStore{
+		"cookie": bhttp.CookieSessionStore{},
+		"memory": bhttp.NewMemorySessionStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mux := sessionMux(store, [][]byte{sessionKey1})
+
+			rec, cookie := serveSession(mux, http.MethodGet, "/visit", nil)
+			require.Equal(t, "1 true", rec.Body.String())
+			require.NotNil(t, cookie)
+			require.True(t, cookie.Secure)
+			require.True(t, cookie.HttpOnly)
+			require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+			require.Equal(t, int(bhttp.DefaultSessionMaxAge/time.Second), cookie.MaxAge)
+
+			rec, cookie = serveSession(mux, http.MethodGet, "/visit", cookie)
+			require.Equal(t, "2 false", rec.Body.String())
+
+			t.Run("unmodified sessions are not saved", func(t *testing.T) {
+				rec, unchanged := serveSession(mux, http.MethodGet, "/peek", cookie)
+				require.Equal(t, "2", rec.Body.String())
+				require.Nil(t, unchanged)
+			})
+
+			t.Run("changes of failed requests are discarded", func(t *testing.T) {
+				rec, failed := serveSession(mux, http.MethodGet, "/fail", cookie)
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+				require.Nil(t, failed)
+
+				rec, _ = serveSession(mux, http.MethodGet, "/peek", cookie)
+				require.Equal(t, "2", rec.Body.String())
+			})
+
+			t.Run("tampered cookies start a new session", func(t *testing.T) {
+				tampered := *cookie
+				tampered.Value += "x"
+
+				rec, _ := serveSession(mux, http.MethodGet, "/visit", &tampered)
+				require.Equal(t, "1 true", rec.Body.String())
+			})
+
+			t.Run("renew keeps values", func(t *testing.T) {
+				_, renewed := serveSession(mux, http.MethodPost, "/login", cookie)
+				require.NotNil(t, renewed)
+				require.NotEqual(t, cookie.Value, renewed.Value)
+
+				rec, _ := serveSession(mux, http.MethodGet, "/peek", renewed)
+				require.Equal(t, "2", rec.Body.String())
+
+				_, ok := store.(*bhttp.MemorySessionStore)
+				if ok {
+					rec, _ = serveSession(mux, http.MethodGet, "/peek", cookie)
+					require.Equal(t, "0", rec.Body.String(), "old session id is revoked")
+				}
+			})
+
+			t.Run("destroy expires the cookie", func(t *testing.T) {
+				_, expired := serveSession(mux, http.MethodPost, "/logout", cookie)
+				require.NotNil(t, expired)
+				require.Equal(t, -1, expired.MaxAge)
+			})
+		})
+	}
+}
+
+func TestSessionsKeyRotation(t *testing.T) {
+	store := bhttp.NewMemorySessionStore()
+
+	_, cookie := serveSession(sessionMux(store, [][]byte{sessionKey1}), http.MethodGet, "/visit", nil)
+
+	rotated := sessionMux(store, [][]byte{sessionKey2, sessionKey1})
+	rec, resigned := serveSession(rotated, http.MethodGet, "/visit", cookie)
+	require.Equal(t, "2 false", rec.Body.String())
+
+	rec, _ = serveSession(sessionMux(store, [][]byte{sessionKey2}), http.MethodGet, "/visit", resigned)
+	require.Equal(t, "3 false", rec.Body.String(), "re-signed with the new key")
+
+	rec, _ = serveSession(sessionMux(store, [][]byte{sessionKey2}), http.MethodGet, "/visit", cookie)
+	require.Equal(t, "1 true", rec.Body.String(), "retired key is no longer accepted")
+}
+
+func TestSessionsEncryption(t *testing.T) {
+	mux := sessionMux(bhttp.CookieSessionStore{}, [][]byte{sessionKey1}, bhttp.WithSessionEncryption(),
+		bhttp.WithSessionCookie(http.Cookie{Name: bhttp.DefaultSessionCookie, Path: "/app"}))
+
+	_, cookie := serveSession(mux, http.MethodGet, "/visit", nil)
+	require.NotContains(t, cookie.Value, ".")
+	require.Equal(t, "/app", cookie.Path)
+
+	rec, _ := serveSession(mux, http.MethodGet, "/visit", cookie)
+	require.Equal(t, "2 false", rec.Body.String())
+
+	plain := sessionMux(bhttp.CookieSessionStore{}, [][]byte{sessionKey1})
+	rec, _ = serveSession(plain, http.MethodGet, "/visit", cookie)
+	require.Equal(t, "1 true", rec.Body.String())
+}
+
+func TestSessionsMaxAge(t *testing.T) {
+	mux := sessionMux(bhttp.NewMemorySessionStore(), [][]byte{sessionKey1}, bhttp.WithSessionMaxAge(time.Nanosecond))
+
+	_, cookie := serveSession(mux, http.MethodGet, "/visit", nil)
+
+	rec, _ := serveSession(mux, http.MethodGet, "/visit", cookie)
+	require.Equal(t, "1 true", rec.Body.String())
+}
+
+func TestSessionsCookieTooLarge(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(bhttp.Sessions(bhttp.CookieSessionStore{}, [][]byte{sessionKey1}))
+	mux.HandleFunc("GET /big", func(ctx context.Context, _ bhttp.ResponseWriter, _ *http.Request) error {
+		sess, err := bhttp.SessionFrom(ctx)
+		if err != nil {
+			return err
+		}
+
+		return sess.Set("big", strings.Repeat("x", 5000))
+	})
+
+	rec, cookie := serveSession(mux, http.MethodGet, "/big", nil)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Nil(t, cookie)
+}
+
+func TestSessionsRequireKeys(t *testing.T) {
+	require.PanicsWithValue(t, "bhttp: sessions require at least one key", func() {
+		bhttp.Sessions(bhttp.CookieSessionStore{}, nil)
+	})
+	require.PanicsWithValue(t, "bhttp: session keys must be at least 32 bytes", func() {
+		bhttp.Sessions(bhttp.CookieSessionStore{}, [][]byte{[]byte("short")})
+	})
+}
+
+func TestSessionFromWithoutMiddleware(t *testing.T) {
+	_, err := bhttp.SessionFrom(context.Background())
+	require.ErrorIs(t, err, bhttp.ErrNoSession)
+}
+
+func TestSessionCSRFStore(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(
+		bhttp.Sessions(bhttp.NewMemorySessionStore(), [][]byte{sessionKey1}),
+		bhttp.CSRF(bhttp.CSRFSynchronizer, bhttp.WithCSRFStore(bhttp.SessionCSRFStore())))
+	mux.HandleFunc("GET /form", func(ctx context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		_, err := fmt.Fprint(w, bhttp.CSRFToken(ctx))
+		return err
+	})
+	mux.HandleFunc("POST /form", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil })
+
+	rec, cookie := serveSession(mux, http.MethodGet, "/form", nil)
+	require.NotNil(t, cookie)
+
+	post := func(token string) int {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.AddCookie(cookie)
+		req.Header.Set(bhttp.DefaultCSRFHeader, token)
+		mux.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, post(rec.Body.String()))
+	require.Equal(t, http.StatusForbidden, post("invalid"))
+}
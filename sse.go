@@ -0,0 +1,177 @@
+package bhttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultSSEHeartbeat is the interval of the heartbeat comments sent by [SSE], unless configured otherwise
+// with [WithSSEHeartbeat].
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// sseLineBreaks normalizes every line terminator of the event stream format to "\n".
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n") //nolint:gochecknoglobals
+
+// Event is a single server-sent event.
+type Event struct {
+	ID    string        // sets the "id" field, which the client sends back as "Last-Event-ID" when it reconnects.
+	Type  string        // sets the "event" field, the type the client listens for. Empty means "message".
+	Data  string        // sets the "data" field, with one field per line.
+	Retry time.Duration // sets the "retry" field, the delay before the client reconnects. Zero omits it.
+}
+
+// SSEOption configures a handler created with [SSE].
+type SSEOption func(*sseOptions)
+
+type sseOptions struct {
+	heartbeat time.Duration
+}
+
+// WithSSEHeartbeat sets the interval at which a comment is sent while no events are, so that proxies do not
+// close an idle connection. It defaults to [DefaultSSEHeartbeat]. Zero disables heartbeats.
+func WithSSEHeartbeat(d time.Duration) SSEOption {
+	return func(o *sseOptions) { o.heartbeat = d }
+}
+
+// EventStream sends server-sent events to the client. It is safe for concurrent use.
+type EventStream struct {
+	ctx         context.Context
+	w           ResponseWriter
+	lastEventID string
+
+	mu      sync.Mutex
+	started bool
+}
+
+// LastEventID returns the "Last-Event-ID" header of the request, the id of the last event the client
+// received before it reconnected. It is empty for new streams, and lets the stream resume after it.
+func (s *EventStream) LastEventID() string { return s.lastEventID }
+
+// Send writes the event and flushes it to the client. The first event commits the response, after which
+// errors can no longer be rendered as an error response. It returns the context error once the client
+// has gone away.
+func (s *EventStream) Send(ev Event) error {
+	if strings.ContainsAny(ev.ID, "\r\n\x00") || strings.ContainsAny(ev.Type, "\r\n") {
+		return errors.Newf("event id and type must not contain line breaks, got: %q, %q", ev.ID, ev.Type)
+	}
+
+	var b strings.Builder
+	if ev.ID != "" {
+		b.WriteString("id: " + ev.ID + "\n")
+	}
+
+	if ev.Type != "" {
+		b.WriteString("event: " + ev.Type + "\n")
+	}
+
+	if ev.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	// "\r\n", "\r" and "\n" all end a line, so each must start a new data field.
+	for _, line := range strings.Split(sseLineBreaks.Replace(ev.Data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// write sends a chunk of the stream, committing the response on the first call.
+func (s *EventStream) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if !s.started {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	if _, err := s.w.Write([]byte(chunk)); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+
+	if err := s.w.FlushError(); err != nil {
+		return errors.Wrap(err, "failed to flush event")
+	}
+
+	return nil
+}
+
+// heartbeat sends a comment, but only once the stream has started so that errors before the first
+// event still render as error responses.
+func (s *EventStream) heartbeat() {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+
+	if started {
+		_ = s.write(": heartbeat\n\n")
+	}
+}
+
+// SSE returns a handler that streams server-sent events by calling 'fn'. The stream ends when 'fn'
+// returns. Errors that 'fn' returns before sending the first event are rendered like those of any other
+// handler. After that, the client going away ends the stream cleanly and other errors are only logged,
+// since the response has been sent already.
+//
+// Because events bypass the response buffer, middleware cannot replace the response once streaming
+// started.
+func SSE(fn func(ctx context.Context, s *EventStream) error, opts ...SSEOption) Handler {
+	o := sseOptions{heartbeat: DefaultSSEHeartbeat}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return HandlerFunc(func(ctx context.Context, w ResponseWriter, r *http.Request) error {
+		stream := &EventStream{ctx: ctx, w: w, lastEventID: r.Header.Get("Last-Event-ID")}
+
+		done, stopped := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+
+			if o.heartbeat <= 0 {
+				return
+			}
+
+			ticker := time.NewTicker(o.heartbeat)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					stream.heartbeat()
+				}
+			}
+		}()
+
+		err := fn(ctx, stream)
+
+		close(done)
+		<-stopped // the writer must not be used once the handler returns.
+
+		if err != nil && stream.started && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return nil // the client went away, which is how streams usually end.
+		}
+
+		return err
+	})
+}
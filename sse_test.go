@@ -0,0 +1,108 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/advdv/bhttp"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSE(t *testing.T) {
+	logs := bhttp.NewTestLogger(t)
+	mux := bhttp.NewServeMuxWith(-1, logs, http.NewServeMux(), bhttp.NewReverser())
+
+	mux.Handle("GET /events", bhttp.SSE(func(ctx context.Context, s *bhttp.EventStream) error {
+		if s.LastEventID() == "unknown" {
+			return bhttp.NewError(bhttp.CodeNotFound, errors.New("unknown event id"))
+		}
+
+		if err := s.Send(bhttp.Event{ID: "1", Type: "progress", Data: "10%", Retry: 2 * time.Second}); err != nil {
+			return err
+		}
+
+		if err := s.Send(bhttp.Event{Data: "line 1\nline 2"}); err != nil {
+			return err
+		}
+
+		if s.LastEventID() == "fail" {
+			return errors.New("failed halfway")
+		}
+
+		return nil
+	}, bhttp.WithSSEHeartbeat(0)))
+
+	t.Run("streams events", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.True(t, rec.Flushed)
+		require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		require.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+		require.Equal(t, "id: 1\nevent: progress\nretry: 2000\ndata: 10%\n\ndata: line 1\ndata: line 2\n\n",
+			rec.Body.String())
+	})
+
+	t.Run("renders errors before the first event", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Last-Event-ID", "unknown")
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "Not Found: unknown event id\n", rec.Body.String())
+	})
+
+	t.Run("logs errors after the first event", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Last-Event-ID", "fail")
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "data: line 2\n\n")
+		require.Equal(t, int64(1), logs.NumLogUnhandledServeError)
+	})
+}
+
+func TestSSELineBreaks(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Handle("GET /events", bhttp.SSE(func(_ context.Context, s *bhttp.EventStream) error {
+		return s.Send(bhttp.Event{Data: "a\rid: evil\r\nb\nc"})
+	}, bhttp.WithSSEHeartbeat(0)))
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil)
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, "data: a\ndata: id: evil\ndata: b\ndata: c\n\n", rec.Body.String())
+}
+
+func TestSSEHeartbeatAndCancel(t *testing.T) {
+	logs := bhttp.NewTestLogger(t)
+	mux := bhttp.NewServeMuxWith(-1, logs, http.NewServeMux(), bhttp.NewReverser())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mux.Handle("GET /events", bhttp.SSE(func(ctx context.Context, s *bhttp.EventStream) error {
+		if err := s.Send(bhttp.Event{Data: "hello"}); err != nil {
+			return err
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		<-ctx.Done()
+
+		return s.Send(bhttp.Event{Data: "never sent"})
+	}, bhttp.WithSSEHeartbeat(10*time.Millisecond)))
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, http.MethodGet, "/events", nil)
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "data: hello\n\n: heartbeat\n\n")
+	require.NotContains(t, rec.Body.String(), "never sent")
+	require.Equal(t, int64(0), logs.NumLogUnhandledServeError)
+}
@@ -0,0 +1,187 @@
+package bhttp
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/advdv/bhttp/internal/httppattern"
+	"github.com/advdv/bhttp/openapi"
+	"github.com/cockroachdb/errors"
+)
+
+// TypedRoute is a route that is declared once, as a value, together with the struct type 'P' that holds its
+// path parameters. The same value registers the handler, extracts the parameters and reverses urls, so a
+// change to the pattern that is not reflected in 'P' is caught when the route is declared rather than when
+// a link breaks. Create it with [Route].
+type TypedRoute[P any] struct {
+	name     string
+	opts     []RouteOption
+	pattern  *httppattern.Pattern
+	fields   []pathField
+	reverser *Reverser // of the mux that the route was registered on, if any.
+}
+
+// pathField links a struct field to a wildcard of the pattern.
+type pathField struct {
+	index []int
+	name  string
+}
+
+// TypedHandlerFunc is the signature of handlers for a [TypedRoute]. It receives the parsed path
+// parameters as its last argument.
+type TypedHandlerFunc[P any] func(ctx context.Context, w ResponseWriter, r *http.Request, params P) error
+
+// Route declares a typed route for 'pattern'. The struct type 'P' must have a field with a `path:"<name>"`
+// tag for every wildcard in the pattern, and no tags for wildcards that do not exist. Fields can be strings,
+// booleans, numbers or implement [encoding.TextUnmarshaler] and [encoding.TextMarshaler]. The options
+// are used when registering the route on a [ServeMux]. Route panics if the pattern and 'P' do not match, so
+// routes are best declared as package-level variables:
+//
+//	var GetItem = bhttp.Route[struct {
+//	    ID int64 `path:"id"`
+//	}]("GET /items/{id}", bhttp.Name("get-item"))
+func Route[P any](pattern string, opts ...RouteOption) *TypedRoute[P] {
+	rt, err := newTypedRoute[P](pattern, opts...)
+	if err != nil {
+		panic("bhttp: " + err.Error())
+	}
+
+	return rt
+}
+
+func newTypedRoute[P any](pattern string, opts ...RouteOption) (*TypedRoute[P], error) {
+	pat, err := httppattern.ParsePattern(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pattern")
+	}
+
+	typ := reflect.TypeFor[P]()
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.Newf("route parameters must be a struct, got: %s", typ)
+	}
+
+	rt := &TypedRoute[P]{pattern: pat, name: newRouteConfig(opts...).name, opts: opts}
+
+	wildcards := pat.Wildcards()
+
+	for _, field := range reflect.VisibleFields(typ) {
+		wildcard, ok := field.Tag.Lookup("path")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case !field.IsExported():
+			return nil, errors.Newf("field %s with path tag must be exported", field.Name)
+		case !slices.Contains(wildcards, wildcard):
+			return nil, errors.Newf("field %s refers to wildcard %q which is not in pattern %q", field.Name, wildcard, pattern)
+		case slices.ContainsFunc(rt.fields, func(f pathField) bool { return f.name == wildcard }):
+			return nil, errors.Newf("field %s refers to wildcard %q which already has a field", field.Name, wildcard)
+		case !canParseValue(field.Type):
+			return nil, errors.Newf("field %s has unsupported type: %s", field.Name, field.Type)
+		case !canFormatValue(field.Type):
+			return nil, errors.Newf("field %s has type %s that cannot be formatted, it must implement encoding.TextMarshaler",
+				field.Name, field.Type)
+		}
+
+		rt.fields = append(rt.fields, pathField{index: field.Index, name: wildcard})
+	}
+
+	for _, wildcard := range wildcards {
+		if !slices.ContainsFunc(rt.fields, func(f pathField) bool { return f.name == wildcard }) {
+			return nil, errors.Newf("no field for wildcard %q of pattern %q", wildcard, pattern)
+		}
+	}
+
+	return rt, nil
+}
+
+// Pattern returns the pattern of the route.
+func (rt *TypedRoute[P]) Pattern() string { return rt.pattern.String() }
+
+// Name returns the name of the route, empty if the route is unnamed.
+func (rt *TypedRoute[P]) Name() string { return rt.name }
+
+// Handle registers the handler 'fn' for the route on 'mux'. Requests with path values that cannot be parsed
+// into 'P' are answered with a [CodeBadRequest] error. From then on, [TypedRoute.Reverse] builds urls with
+// the options of the reverser of 'mux'.
+func (rt *TypedRoute[P]) Handle(mux *ServeMux, fn TypedHandlerFunc[P]) {
+	mux.Handle(rt.Pattern(), rt.Handler(fn), rt.opts...)
+	rt.reverser = mux.reverser
+}
+
+// Handler returns a [Handler] that parses the parameters of the route and calls 'fn' with them.
+func (rt *TypedRoute[P]) Handler(fn TypedHandlerFunc[P]) Handler {
+	return &typedHandler[P]{route: rt, fn: fn}
+}
+
+// typedHandler implements [Handler] for [TypedRoute.Handler].
+type typedHandler[P any] struct {
+	route *TypedRoute[P]
+	fn    TypedHandlerFunc[P]
+}
+
+// ServeBHTTP implements the [Handler] interface.
+func (h *typedHandler[P]) ServeBHTTP(ctx context.Context, w ResponseWriter, r *http.Request) error {
+	params, err := h.route.Params(r)
+	if err != nil {
+		return err
+	}
+
+	return h.fn(ctx, w, r, params)
+}
+
+// describeOperation implements the operationDescriber interface.
+func (h *typedHandler[P]) describeOperation(op *openapi.Operation, gen *openapi.Generator) {
+	typ := reflect.TypeFor[P]()
+	for _, field := range h.route.fields {
+		op.Parameter(field.name, "path").Schema = gen.Schema(typ.FieldByIndex(field.index).Type)
+	}
+
+	errorResponses(op, CodeBadRequest)
+}
+
+// Params parses the path values of 'r' into 'P'. It returns a [CodeBadRequest] error that names the
+// offending parameter if a value cannot be parsed.
+func (rt *TypedRoute[P]) Params(r *http.Request) (P, error) {
+	var params P
+
+	v := reflect.ValueOf(&params).Elem()
+	for _, field := range rt.fields {
+		if err := parseValue(v.FieldByIndex(field.index), r.PathValue(field.name)); err != nil {
+			return params, paramError("path parameter", field.name, err)
+		}
+	}
+
+	return params, nil
+}
+
+// Reverse builds the url for the route from 'params'. Once the route is registered with [TypedRoute.Handle],
+// the url is built like the reverser of the mux would, so options such as [RejectSlashes] apply.
+func (rt *TypedRoute[P]) Reverse(params P) (string, error) {
+	v := reflect.ValueOf(params)
+
+	vals := make(map[string]string, len(rt.fields))
+	for _, field := range rt.fields {
+		s, err := formatValue(v.FieldByIndex(field.index))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to format parameter %q", field.name)
+		}
+
+		vals[field.name] = s
+	}
+
+	var builder httppattern.Builder
+	if rt.reverser != nil {
+		builder = rt.reverser.builder
+	}
+
+	res, err := builder.BuildNamed(rt.pattern, vals)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to build")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,150 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type itemParams struct {
+	Org  string `path:"org"`
+	ID   int64  `path:"id"`
+	Note string // not a path parameter
+}
+
+var getItem = bhttp.Route[itemParams]("GET /orgs/{org}/items/{id}", bhttp.Name("get-org-item"))
+
+func TestTypedRoute(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	getItem.Handle(mux, func(ctx context.Context, w bhttp.ResponseWriter, r *http.Request, p itemParams) error {
+		fmt.Fprintf(w, "%s:%d", p.Org, p.ID)
+		return nil
+	})
+
+	t.Run("serves typed params", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/items/42", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "acme:42", rec.Body.String())
+	})
+
+	t.Run("rejects invalid params", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/items/abc", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, "Bad Request: invalid path parameter \"id\": must be an integer\n", rec.Body.String())
+	})
+
+	t.Run("reverses", func(t *testing.T) {
+		loc, err := getItem.Reverse(itemParams{Org: "a/b", ID: 7})
+		require.NoError(t, err)
+		require.Equal(t, "/orgs/a%2Fb/items/7", loc)
+
+		byName, err := mux.Reverse("get-org-item", "a/b", "7")
+		require.NoError(t, err)
+		require.Equal(t, loc, byName)
+	})
+
+	t.Run("round-trips", func(t *testing.T) {
+		loc, err := getItem.Reverse(itemParams{Org: "x y?", ID: -3})
+		require.NoError(t, err)
+
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, loc, nil)
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, "x y?:-3", rec.Body.String())
+	})
+}
+
+func TestTypedRouteReverseWithMuxOptions(t *testing.T) {
+	route := bhttp.Route[itemParams]("GET /orgs/{org}/items/{id}")
+
+	loc, err := route.Reverse(itemParams{Org: "a/b", ID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "/orgs/a%2Fb/items/1", loc)
+
+	mux := bhttp.NewServeMuxWith(-1, bhttp.NewTestLogger(t), http.NewServeMux(), bhttp.NewReverser(bhttp.RejectSlashes()))
+	route.Handle(mux, func(context.Context, bhttp.ResponseWriter, *http.Request, itemParams) error { return nil })
+
+	_, err = route.Reverse(itemParams{Org: "a/b", ID: 1})
+	require.Error(t, err)
+}
+
+// slug only implements encoding.TextUnmarshaler, so it can be parsed but not formatted.
+type slug struct{ s string }
+
+func (s *slug) UnmarshalText(text []byte) error { s.s = string(text); return nil }
+
+// code implements both encoding.TextUnmarshaler and encoding.TextMarshaler with a pointer receiver.
+type code struct{ s string }
+
+func (c *code) UnmarshalText(text []byte) error { c.s = strings.ToLower(string(text)); return nil }
+
+func (c *code) MarshalText() ([]byte, error) { return []byte(strings.ToUpper(c.s)), nil }
+
+func TestTypedRoutePointerMarshaler(t *testing.T) {
+	type params struct {
+		Code code `path:"code"`
+	}
+
+	route := bhttp.Route[params]("GET /codes/{code}")
+
+	mux := bhttp.NewServeMux()
+	route.Handle(mux, func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request, p params) error {
+		_, err := fmt.Fprint(w, p.Code.s)
+		return err
+	})
+
+	loc, err := route.Reverse(params{Code: code{s: "abc"}})
+	require.NoError(t, err)
+	require.Equal(t, "/codes/ABC", loc)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, loc, nil)
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, "abc", rec.Body.String())
+}
+
+func TestTypedRouteDeclarationErrors(t *testing.T) {
+	assert.PanicsWithValue(t, `bhttp: no field for wildcard "id" of pattern "/items/{id}"`, func() {
+		bhttp.Route[struct{}]("/items/{id}")
+	})
+
+	assert.PanicsWithValue(t, `bhttp: field ID refers to wildcard "idd" which is not in pattern "/items/{id}"`, func() {
+		bhttp.Route[struct {
+			ID string `path:"idd"`
+		}]("/items/{id}")
+	})
+
+	assert.PanicsWithValue(t, `bhttp: field B refers to wildcard "id" which already has a field`, func() {
+		bhttp.Route[struct {
+			A string `path:"id"`
+			B string `path:"id"`
+		}]("/items/{id}")
+	})
+
+	assert.PanicsWithValue(t, `bhttp: field ID has unsupported type: []string`, func() {
+		bhttp.Route[struct {
+			ID []string `path:"id"`
+		}]("/items/{id}")
+	})
+
+	assert.PanicsWithValue(t,
+		"bhttp: field ID has type bhttp_test.slug that cannot be formatted, it must implement encoding.TextMarshaler",
+		func() {
+			bhttp.Route[struct {
+				ID slug `path:"id"`
+			}]("/items/{id}")
+		})
+
+	assert.PanicsWithValue(t, `bhttp: route parameters must be a struct, got: string`, func() {
+		bhttp.Route[string]("/items")
+	})
+}
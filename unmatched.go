@@ -0,0 +1,107 @@
+package bhttp
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/advdv/bhttp/internal/httppattern"
+	"github.com/cockroachdb/errors"
+)
+
+// probeMethods are always tried when determining which methods are allowed for a path, so that
+// patterns registered directly on the underlying [http.ServeMux] are also considered.
+var probeMethods = []string{ //nolint:gochecknoglobals
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// isUnmatched reports whether the underlying mux would answer the request with a plain "not found" or
+// "method not allowed" response. Requests that are redirected (to a cleaned path, or to a path with a
+// trailing slash) are left to the underlying mux.
+func (m *ServeMux) isUnmatched(r *http.Request) bool {
+	if r.RequestURI == "*" {
+		return false
+	}
+
+	if _, pattern := m.mux.Handler(r); pattern != "" {
+		return false
+	}
+
+	if r.Method != http.MethodConnect {
+		escaped := r.URL.EscapedPath()
+		if httppattern.CleanPath(escaped) != escaped {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allowedMethods returns the sorted methods for which a pattern matches the path of the request, taking the
+// constraints of wildcards into account.
+func (m *ServeMux) allowedMethods(r *http.Request) []string {
+	candidates := slices.Clone(probeMethods)
+	for _, rt := range m.routes {
+		if rt.Method != "" && !slices.Contains(candidates, rt.Method) {
+			candidates = append(candidates, rt.Method)
+		}
+	}
+
+	var allowed []string
+
+	for _, method := range candidates {
+		if method == r.Method {
+			continue
+		}
+
+		probe := new(http.Request)
+		*probe = *r
+		probe.Method = method
+
+		handler, pattern := m.mux.Handler(probe)
+		if pattern == "" {
+			continue
+		}
+
+		if group, ok := handler.(*routeGroup); ok && !group.matches(probe) {
+			continue // the path has the right shape, but no constraint is satisfied.
+		}
+
+		allowed = append(allowed, method)
+	}
+
+	slices.Sort(allowed)
+
+	return allowed
+}
+
+// serveUnmatched answers a request that matched no pattern.
+func (m *ServeMux) serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	m.serveError(w, r, m.unmatchedError)
+}
+
+// serveError answers the request with the error returned by 'errf'. It runs through the same middleware and
+// error handling as a registered handler so that "not found" and "method not allowed" responses are logged,
+// decorated and rendered like any other error.
+func (m *ServeMux) serveError(w http.ResponseWriter, r *http.Request, errf func(r *http.Request) error) {
+	ToStd(wrapBare(BareHandlerFunc(func(_ ResponseWriter, r *http.Request) error {
+		return errf(r)
+	}), m.middlewares.buffered...), m.bufLimit, m.logs).ServeHTTP(w, r)
+}
+
+func (m *ServeMux) unmatchedError(r *http.Request) error {
+	allowed := m.allowedMethods(r)
+	if len(allowed) < 1 {
+		return notFoundError(r)
+	}
+
+	err := NewError(CodeMethodNotAllowed, errors.Newf("method %s is not allowed", r.Method))
+	err.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	return err
+}
+
+func notFoundError(*http.Request) error {
+	return NewError(CodeNotFound, errors.New("no route matches the request path"))
+}
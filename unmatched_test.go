@@ -0,0 +1,107 @@
+package bhttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func newUnmatchedMux(t *testing.T) (*bhttp.ServeMux, *[]bhttp.Code) {
+	t.Helper()
+
+	var seen []bhttp.Code
+
+	mux := bhttp.NewServeMux()
+	mux.Use(func(next bhttp.BareHandler) bhttp.BareHandler {
+		return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+			w.Header().Set("X-Seen-By", "middleware")
+
+			err := next.ServeBareBHTTP(w, r)
+			seen = append(seen, bhttp.CodeOf(err))
+
+			return err
+		})
+	})
+
+	noop := func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil }
+	mux.HandleFunc("GET /items/{id}", noop)
+	mux.HandleFunc("DELETE /items/{id}", noop)
+	mux.HandleFunc("GET /dir/", noop)
+
+	return mux, &seen
+}
+
+func TestNotFoundThroughMiddleware(t *testing.T) {
+	mux, seen := newUnmatchedMux(t)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bogus", nil)
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "Not Found: no route matches the request path\n", rec.Body.String())
+	require.Empty(t, rec.Header().Get("X-Seen-By"))
+	require.Equal(t, []bhttp.Code{bhttp.CodeNotFound}, *seen)
+}
+
+func TestMethodNotAllowedThroughMiddleware(t *testing.T) {
+	mux, seen := newUnmatchedMux(t)
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/1", nil)
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "DELETE, GET, HEAD", rec.Header().Get("Allow"))
+	require.Equal(t, "Method Not Allowed: method POST is not allowed\n", rec.Body.String())
+	require.Equal(t, []bhttp.Code{bhttp.CodeMethodNotAllowed}, *seen)
+}
+
+func TestUnmatchedCustomRendering(t *testing.T) {
+	mux := bhttp.NewServeMux()
+	mux.Use(func(next bhttp.BareHandler) bhttp.BareHandler {
+		return bhttp.BareHandlerFunc(func(w bhttp.ResponseWriter, r *http.Request) error {
+			if err := next.ServeBareBHTTP(w, r); bhttp.CodeOf(err) == bhttp.CodeNotFound {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":"not_found"}`)
+
+				return nil
+			}
+
+			return nil
+		})
+	})
+
+	rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bogus", nil)
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"error":"not_found"}`, rec.Body.String())
+}
+
+func TestUnmatchedKeepsRedirects(t *testing.T) {
+	mux, seen := newUnmatchedMux(t)
+
+	t.Run("trailing slash", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/dir", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+		require.Equal(t, "/dir/", rec.Header().Get("Location"))
+	})
+
+	t.Run("unclean path", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo/../bogus", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+		require.Equal(t, "/bogus", rec.Header().Get("Location"))
+	})
+
+	require.Empty(t, *seen)
+}
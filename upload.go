@@ -0,0 +1,276 @@
+package bhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Defaults for the limits of [ParseUpload] that are left zero in [UploadLimits]. Together they bound the
+// memory that form values can take.
+const (
+	DefaultUploadFieldLimit = 64 << 10
+	DefaultUploadTotalLimit = 32 << 20
+	DefaultUploadMaxParts   = 100
+)
+
+// sniffLen is the number of bytes [http.DetectContentType] considers.
+const sniffLen = 512
+
+// UploadLimits bounds a multipart upload. Zero values use a safe default, and negative values remove a
+// limit, for example for routes that accept very large files from trusted clients.
+type UploadLimits struct {
+	// FileLimit is the maximum size of a single file in bytes. Zero means only the total limit applies.
+	FileLimit int64
+	// TotalLimit is the maximum size of all files and form values together, in bytes. It defaults to
+	// [DefaultUploadTotalLimit].
+	TotalLimit int64
+	// FieldLimit is the maximum size of a single non-file form value in bytes. It defaults to
+	// [DefaultUploadFieldLimit].
+	FieldLimit int64
+	// MaxParts is the maximum number of parts, files and form values together. It defaults to
+	// [DefaultUploadMaxParts].
+	MaxParts int
+	// ContentTypes lists the media types files may have, such as "image/png" or "image/*". The type is
+	// determined by sniffing the content, not from what the client claims. When empty, any type is
+	// accepted.
+	ContentTypes []string
+}
+
+// UploadSink stores the files of an upload.
+type UploadSink interface {
+	// Store saves the content of 'file' and returns where it was stored, such as a path or object key.
+	// When reading the content fails, Store must clean up what it stored and return an error.
+	Store(ctx context.Context, file *UploadedFile, content io.Reader) (location string, err error)
+	// Remove deletes a file that was stored before.
+	Remove(ctx context.Context, location string) error
+}
+
+// UploadedFile describes a file of an upload.
+type UploadedFile struct {
+	Field       string // name of the form field.
+	Filename    string // file name as given by the client.
+	ContentType string // sniffed content type.
+	Size        int64  // size in bytes, set once the file is stored.
+	Location    string // where the sink stored the file, set once the file is stored.
+}
+
+// Upload is the result of [ParseUpload].
+type Upload struct {
+	Values map[string][]string // non-file form values.
+	Files  []*UploadedFile     // files in the order they were sent.
+
+	sink UploadSink
+}
+
+// Remove deletes all files of the upload from the sink, for example once they have been processed.
+func (u *Upload) Remove(ctx context.Context) error {
+	var errs []error
+	for _, file := range u.Files {
+		errs = append(errs, u.sink.Remove(ctx, file.Location))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseUpload streams a "multipart/form-data" request body into 'sink', without buffering files in
+// memory. A nil sink stores files in the temporary directory, see [TempDirSink].
+//
+// Requests that are not multipart are answered with [CodeUnsupportedMediaType], as are files with a
+// content type that is not allowed. Exceeding a size limit or the maximum number of parts results in
+// [CodeRequestEntityTooLarge], and a malformed body in [CodeBadRequest]. When an error is returned, files
+// stored so far have been removed from the sink.
+func ParseUpload(r *http.Request, sink UploadSink, limits UploadLimits) (*Upload, error) {
+	if sink == nil {
+		sink = TempDirSink{}
+	}
+
+	limits = limits.withDefaults()
+
+	upload := &Upload{Values: map[string][]string{}, sink: sink}
+	if err := upload.parse(r, limits); err != nil {
+		_ = upload.Remove(context.WithoutCancel(r.Context()))
+
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// withDefaults returns the limits with defaults for the zero values.
+func (l UploadLimits) withDefaults() UploadLimits {
+	if l.FieldLimit == 0 {
+		l.FieldLimit = DefaultUploadFieldLimit
+	}
+
+	if l.TotalLimit == 0 {
+		l.TotalLimit = DefaultUploadTotalLimit
+	}
+
+	if l.MaxParts == 0 {
+		l.MaxParts = DefaultUploadMaxParts
+	}
+
+	return l
+}
+
+func (u *Upload) parse(r *http.Request, limits UploadLimits) error {
+	mr, err := r.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return NewError(CodeUnsupportedMediaType, errors.Newf("content type %q is not supported, expect: multipart/form-data",
+			r.Header.Get("Content-Type")))
+	} else if err != nil {
+		return NewError(CodeBadRequest, errors.Wrap(err, "invalid multipart body"))
+	}
+
+	var total int64
+
+	for parts := 1; ; parts++ {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return NewError(CodeBadRequest, errors.Wrap(err, "invalid multipart body"))
+		}
+
+		if limits.MaxParts > 0 && parts > limits.MaxParts {
+			return NewError(CodeRequestEntityTooLarge, errors.Newf("request has more than %d parts", limits.MaxParts))
+		}
+
+		content := &uploadReader{r: part, name: part.FormName(), total: &total, totalLimit: limits.TotalLimit}
+		if part.FileName() == "" {
+			content.limit, content.kind = limits.FieldLimit, "form value"
+			if err := u.addValue(part.FormName(), content); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		content.limit, content.kind = limits.FileLimit, "file"
+		if err := u.addFile(r.Context(), part.FormName(), part.FileName(), content, limits); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *Upload) addValue(name string, content *uploadReader) error {
+	value, err := io.ReadAll(content)
+	if err != nil {
+		return content.failure(err)
+	}
+
+	u.Values[name] = append(u.Values[name], string(value))
+
+	return nil
+}
+
+func (u *Upload) addFile(ctx context.Context, field, filename string, content *uploadReader, limits UploadLimits) error {
+	head := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return content.failure(err)
+	}
+
+	file := &UploadedFile{Field: field, Filename: filename, ContentType: http.DetectContentType(head[:n])}
+	if len(limits.ContentTypes) > 0 && !mediaTypeAllowed(limits.ContentTypes, file.ContentType) {
+		return NewError(CodeUnsupportedMediaType, errors.Newf("file %q has content type %q, expect one of: %s",
+			filename, file.ContentType, strings.Join(limits.ContentTypes, ", ")))
+	}
+
+	location, err := u.sink.Store(ctx, file, io.MultiReader(bytes.NewReader(head[:n]), content))
+	if err != nil {
+		return content.failure(errors.Wrapf(err, "failed to store file %q", filename))
+	}
+
+	file.Size, file.Location = content.n, location
+	u.Files = append(u.Files, file)
+
+	return nil
+}
+
+// uploadReader reads the content of a single part while enforcing the size limits.
+type uploadReader struct {
+	r          io.Reader
+	kind, name string
+	n          int64
+	limit      int64
+	total      *int64
+	totalLimit int64
+	exceeded   *Error
+	readErr    error
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	*u.total += int64(n)
+
+	switch {
+	case u.limit > 0 && u.n > u.limit:
+		u.exceeded = NewError(CodeRequestEntityTooLarge, errors.Newf("%s %q exceeds %d bytes", u.kind, u.name, u.limit))
+	case u.totalLimit > 0 && *u.total > u.totalLimit:
+		u.exceeded = NewError(CodeRequestEntityTooLarge, errors.Newf("upload exceeds %d bytes", u.totalLimit))
+	default:
+		if err != nil && !errors.Is(err, io.EOF) {
+			u.readErr = err
+		}
+
+		return n, err //nolint:wrapcheck
+	}
+
+	return n, u.exceeded
+}
+
+// failure returns the error to report when reading or storing the part failed with 'err': a client
+// error when the part exceeded a limit or could not be read, and 'err' itself otherwise.
+func (u *uploadReader) failure(err error) error {
+	switch {
+	case u.exceeded != nil:
+		return u.exceeded
+	case u.readErr != nil:
+		return NewError(CodeBadRequest, errors.Wrap(u.readErr, "invalid multipart body"))
+	default:
+		return err
+	}
+}
+
+// TempDirSink is an [UploadSink] that stores files in a directory on disk, the default temporary
+// directory when Dir is empty. The location of a file is its path.
+type TempDirSink struct {
+	Dir string
+}
+
+// Store implements [UploadSink].
+func (s TempDirSink) Store(_ context.Context, _ *UploadedFile, content io.Reader) (string, error) {
+	f, err := os.CreateTemp(s.Dir, "bhttp-upload-*")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create file")
+	}
+
+	if _, err := io.Copy(f, content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return "", errors.Wrap(err, "failed to write file")
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+
+		return "", errors.Wrap(err, "failed to close file")
+	}
+
+	return f.Name(), nil
+}
+
+// Remove implements [UploadSink].
+func (s TempDirSink) Remove(_ context.Context, location string) error {
+	return errors.Wrap(os.Remove(location), "failed to remove file")
+}
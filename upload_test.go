@@ -0,0 +1,133 @@
+package bhttp_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+type uploadPart struct {
+	field, filename string
+	content         []byte
+}
+
+func newUploadRequest(t *testing.T, parts ...uploadPart) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+
+	mw := multipart.NewWriter(&body)
+	for _, part := range parts {
+		if part.filename == "" {
+			require.NoError(t, mw.WriteField(part.field, string(part.content)))
+			continue
+		}
+
+		w, err := mw.CreateFormFile(part.field, part.filename)
+		require.NoError(t, err)
+		_, err = w.Write(part.content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return req
+}
+
+func TestParseUpload(t *testing.T) {
+	dir := t.TempDir()
+	sink := bhttp.TempDirSink{Dir: dir}
+	limits := bhttp.UploadLimits{FileLimit: 1024, TotalLimit: 1500, MaxParts: 3, ContentTypes: []string{"image/*"}}
+
+	png := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 600)...)
+
+	upload, err := bhttp.ParseUpload(newUploadRequest(t,
+		uploadPart{field: "title", content: []byte("holiday")},
+		uploadPart{field: "photo", filename: "a.png", content: png},
+	), sink, limits)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"title": {"holiday"}}, upload.Values)
+	require.Len(t, upload.Files, 1)
+
+	file := upload.Files[0]
+	require.Equal(t, "photo", file.Field)
+	require.Equal(t, "a.png", file.Filename)
+	require.Equal(t, "image/png", file.ContentType)
+	require.Equal(t, int64(len(png)), file.Size)
+
+	stored, err := os.ReadFile(file.Location)
+	require.NoError(t, err)
+	require.Equal(t, png, stored)
+
+	require.NoError(t, upload.Remove(context.Background()))
+	require.NoFileExists(t, file.Location)
+
+	for _, test := range []struct {
+		name     string
+		req      *http.Request
+		wantCode bhttp.Code
+		wantErr  string
+	}{
+		{"file too large", newUploadRequest(t,
+			uploadPart{field: "a", filename: "a.png", content: png},
+			uploadPart{field: "b", filename: "b.png", content: append(png, png...)},
+		), bhttp.CodeRequestEntityTooLarge, `file "b" exceeds 1024 bytes`},
+		{"total too large", newUploadRequest(t,
+			uploadPart{field: "a", filename: "a.png", content: png},
+			uploadPart{field: "b", filename: "b.png", content: png},
+			uploadPart{field: "c", filename: "c.png", content: png},
+		), bhttp.CodeRequestEntityTooLarge, "upload exceeds 1500 bytes"},
+		{"too many parts", newUploadRequest(t,
+			uploadPart{field: "a"}, uploadPart{field: "b"}, uploadPart{field: "c"}, uploadPart{field: "d"},
+		), bhttp.CodeRequestEntityTooLarge, "request has more than 3 parts"},
+		{"sniffed type not allowed", newUploadRequest(t,
+			uploadPart{field: "a", filename: "fake.png", content: []byte("just text")},
+		), bhttp.CodeUnsupportedMediaType, `file "fake.png" has content type "text/plain; charset=utf-8", expect one of: image/*`},
+		{"not multipart", httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("x")),
+			bhttp.CodeUnsupportedMediaType, "expect: multipart/form-data"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := bhttp.ParseUpload(test.req, sink, limits)
+			require.Equal(t, test.wantCode, bhttp.CodeOf(err))
+			require.ErrorContains(t, err, test.wantErr)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, entries, "stored files must be removed")
+		})
+	}
+
+	t.Run("default part limit", func(t *testing.T) {
+		parts := make([]uploadPart, bhttp.DefaultUploadMaxParts+1)
+		for i := range parts {
+			parts[i] = uploadPart{field: "v"}
+		}
+
+		_, err := bhttp.ParseUpload(newUploadRequest(t, parts...), sink, bhttp.UploadLimits{})
+		require.Equal(t, bhttp.CodeRequestEntityTooLarge, bhttp.CodeOf(err))
+
+		_, err = bhttp.ParseUpload(newUploadRequest(t, parts...), sink, bhttp.UploadLimits{MaxParts: -1})
+		require.NoError(t, err)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--xyz\r\nbroken"))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+
+		_, err := bhttp.ParseUpload(req, sink, limits)
+		require.Equal(t, bhttp.CodeBadRequest, bhttp.CodeOf(err))
+	})
+}
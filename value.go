@@ -0,0 +1,127 @@
+package bhttp
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]() //nolint:gochecknoglobals
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()   //nolint:gochecknoglobals
+)
+
+// canParseValue reports whether values of type 't' can be parsed from text by [parseValue].
+func canParseValue(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	return isBasicKind(t.Kind())
+}
+
+// isBasicKind reports whether values of kind 'k' are parsed and formatted with the strconv package.
+func isBasicKind(k reflect.Kind) bool {
+	switch k { //nolint:exhaustive
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// canFormatValue reports whether values of type 't' can be formatted as text by [formatValue].
+func canFormatValue(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textMarshalerType) {
+		return true
+	}
+
+	return isBasicKind(t.Kind())
+}
+
+// parseValue sets the addressable value 'v' from its textual representation 's'.
+func parseValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s)) //nolint:wrapcheck
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+
+		v.SetFloat(f)
+	default:
+		return errors.Newf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// formatValue returns the textual representation of 'v', the inverse of [parseValue]. Like [parseValue], it
+// also accepts types that implement [encoding.TextMarshaler] with a pointer receiver.
+func formatValue(v reflect.Value) (string, error) {
+	if !v.Type().Implements(textMarshalerType) && reflect.PointerTo(v.Type()).Implements(textMarshalerType) {
+		if !v.CanAddr() {
+			addressable := reflect.New(v.Type()).Elem()
+			addressable.Set(v)
+			v = addressable
+		}
+
+		v = v.Addr()
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText() //nolint:forcetypeassert
+		if err != nil {
+			return "", errors.Wrap(err, "failed to marshal text")
+		}
+
+		return string(text), nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		return "", errors.Newf("unsupported type: %s", v.Type())
+	}
+}
@@ -0,0 +1,181 @@
+package bhttp
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// URLReverser builds urls for named routes, as implemented by [ServeMux] and [Reverser].
+type URLReverser interface {
+	Reverse(name string, vals ...string) (string, error)
+}
+
+// ViewOption configures [Views].
+type ViewOption func(*Views)
+
+// Views renders html templates from a file system. Every page is parsed together with the layouts and
+// partials, so a page can invoke a layout that in turn invokes blocks defined by the page. Each file is
+// also a template named by its path, such as "partials/nav.html":
+//
+//	{{/* layouts/base.html */}}
+//	{{define "base"}}<html><body>{{block "content" .}}{{end}}</body></html>{{end}}
+//
+//	{{/* users.html */}}
+//	{{template "base" .}}
+//	{{define "content"}}<a href="{{url "get-user" .ID}}">{{.Name}}</a>{{end}}
+type Views struct {
+	fsys     fs.FS
+	layouts  []string
+	partials []string
+	funcs    template.FuncMap
+	reload   bool
+
+	mu    sync.Mutex
+	pages map[string]*template.Template
+}
+
+// WithViewLayouts sets the glob patterns of the layout templates, "layouts/*.html" by default.
+func WithViewLayouts(patterns ...string) ViewOption {
+	return func(v *Views) { v.layouts = patterns }
+}
+
+// WithViewPartials sets the glob patterns of the partial templates, "partials/*.html" by default.
+func WithViewPartials(patterns ...string) ViewOption {
+	return func(v *Views) { v.partials = patterns }
+}
+
+// WithViewFuncs adds functions that templates can call.
+func WithViewFuncs(funcs template.FuncMap) ViewOption {
+	return func(v *Views) {
+		for name, fn := range funcs {
+			v.funcs[name] = fn
+		}
+	}
+}
+
+// WithViewReverse provides the "url" template function, which builds the url of a named route from its
+// arguments: {{url "get-user" .ID}}. Arguments are formatted with [fmt.Sprint].
+func WithViewReverse(r URLReverser) ViewOption {
+	return func(v *Views) {
+		v.funcs["url"] = func(name string, vals ...any) (string, error) {
+			strs := make([]string, len(vals))
+			for i, val := range vals {
+				strs[i] = fmt.Sprint(val)
+			}
+
+			return r.Reverse(name, strs...)
+		}
+	}
+}
+
+// WithViewReload parses the templates on every render instead of once, so that changes show up without a
+// restart. It is meant for development, together with a file system such as [os.DirFS].
+func WithViewReload(enabled bool) ViewOption {
+	return func(v *Views) { v.reload = enabled }
+}
+
+// NewViews inits views that read templates from 'fsys'.
+func NewViews(fsys fs.FS, opts ...ViewOption) *Views {
+	v := &Views{
+		fsys:     fsys,
+		layouts:  []string{"layouts/*.html"},
+		partials: []string{"partials/*.html"},
+		funcs:    template.FuncMap{},
+		pages:    map[string]*template.Template{},
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Render writes the page template 'name', a path in the file system, with the given status code. The
+// output goes to the response buffer, so when executing the template fails the error is returned and the
+// partially rendered page is discarded together with the buffer.
+func (v *Views) Render(w ResponseWriter, status int, name string, data any) error {
+	page, err := v.page(name)
+	if err != nil {
+		return err
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	w.WriteHeader(status)
+
+	if err := page.ExecuteTemplate(w, name, data); err != nil {
+		return errors.Wrapf(err, "failed to execute template %q", name)
+	}
+
+	return nil
+}
+
+// page returns the parsed page template 'name'.
+func (v *Views) page(name string) (*template.Template, error) {
+	if v.reload {
+		return v.parse(name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if page, ok := v.pages[name]; ok {
+		return page, nil
+	}
+
+	page, err := v.parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	v.pages[name] = page
+
+	return page, nil
+}
+
+// parse parses the page template 'name' together with the layouts and partials. Every file becomes a
+// template that is named by its path in the file system, so files with the same base name in different
+// directories do not replace each other.
+func (v *Views) parse(name string) (*template.Template, error) {
+	page := template.New(name).Funcs(v.funcs)
+
+	for _, pattern := range append(append([]string{}, v.layouts...), v.partials...) {
+		matches, err := fs.Glob(v.fsys, pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid template pattern %q", pattern)
+		}
+
+		for _, match := range matches {
+			if err := v.parseFile(page.New(match), match); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := v.parseFile(page, name); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// parseFile parses the file 'name' into 'tmpl'.
+func (v *Views) parseFile(tmpl *template.Template, name string) error {
+	data, err := fs.ReadFile(v.fsys, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read template %q", name)
+	}
+
+	if _, err := tmpl.Parse(string(data)); err != nil {
+		return errors.Wrapf(err, "failed to parse template %q", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package bhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/advdv/bhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func newViewsFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html": {Data: []byte(
+			`{{define "base"}}<title>{{block "title" .}}Admin{{end}}</title>{{block "content" .}}{{end}}{{end}}`)},
+		"partials/user.html": {Data: []byte(
+			`{{define "user"}}<a href="{{url "get-user" .ID}}">{{.Name}}</a>{{end}}`)},
+		"users/list.html": {Data: []byte(
+			`{{template "base" .}}{{define "content"}}{{range .}}{{template "user" .}}{{end}}{{end}}`)},
+		"users/broken.html": {Data: []byte(
+			`{{template "base" .}}{{define "content"}}<p>partial output</p>{{.Missing.Field}}{{end}}`)},
+	}
+}
+
+func TestViews(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	fsys := newViewsFS()
+	mux := bhttp.NewServeMux()
+	views := bhttp.NewViews(fsys, bhttp.WithViewReverse(mux))
+
+	mux.HandleFunc("GET /users/{id}", func(context.Context, bhttp.ResponseWriter, *http.Request) error { return nil },
+		bhttp.Name("get-user"))
+	mux.HandleFunc("GET /users", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		return views.Render(w, http.StatusOK, "users/list.html", []user{{1, "Alice"}, {2, "<Bob>"}})
+	})
+	mux.HandleFunc("GET /broken", func(_ context.Context, w bhttp.ResponseWriter, _ *http.Request) error {
+		return views.Render(w, http.StatusOK, "users/broken.html", []user{})
+	})
+
+	t.Run("renders page with layout and partials", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Equal(t, `<title>Admin</title><a href="/users/1">Alice</a><a href="/users/2">&lt;Bob&gt;</a>`,
+			rec.Body.String())
+	})
+
+	t.Run("discards output on execution error", func(t *testing.T) {
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil)
+		mux.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.NotContains(t, rec.Body.String(), "partial output")
+	})
+
+	t.Run("caches templates", func(t *testing.T) {
+		fsys["layouts/base.html"] = &fstest.MapFile{Data: []byte(`{{define "base"}}changed{{end}}`)}
+
+		rec, req := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil)
+		mux.ServeHTTP(rec, req)
+		require.True(t, strings.HasPrefix(rec.Body.String(), "<title>Admin</title>"))
+	})
+}
+
+func TestViewsReload(t *testing.T) {
+	fsys := fstest.MapFS{"page.html": {Data: []byte(`v1`)}}
+	views := bhttp.NewViews(fsys, bhttp.WithViewReload(true))
+
+	render := func() string {
+		rec := httptest.NewRecorder()
+		w := bhttp.NewResponseWriter(rec, -1)
+		defer w.Free()
+
+		require.NoError(t, views.Render(w, http.StatusOK, "page.html", nil))
+		require.NoError(t, w.FlushBuffer())
+
+		return rec.Body.String()
+	}
+
+	require.Equal(t, "v1", render())
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte(`v2`)}
+	require.Equal(t, "v2", render())
+}
+
+func TestViewsSameBaseName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html":    {Data: []byte(`page {{template "partials/index.html"}}`)},
+		"partials/index.html": {Data: []byte(`partial`)},
+	}
+	views := bhttp.NewViews(fsys)
+
+	rec := httptest.NewRecorder()
+	w := bhttp.NewResponseWriter(rec, -1)
+	defer w.Free()
+
+	require.NoError(t, views.Render(w, http.StatusOK, "pages/index.html", nil))
+	require.NoError(t, w.FlushBuffer())
+	require.Equal(t, "page partial", rec.Body.String())
+}